@@ -3,6 +3,7 @@ package meta
 import (
 	"context"
 	"errors"
+	"maps"
 	"os"
 	"os/signal"
 	"sync"
@@ -11,6 +12,7 @@ import (
 
 	"github.com/byteweap/meta/component/log"
 	"github.com/byteweap/meta/component/registry"
+	"github.com/byteweap/meta/server"
 )
 
 type AppInfo interface {
@@ -168,11 +170,19 @@ func (a *App) buildInstance() error {
 			endpoints = append(endpoints, e.String())
 		}
 	}
+	metadata := make(map[string]string, len(a.opts.metadata))
+	for _, srv := range a.opts.servers {
+		if p, ok := srv.(server.MetadataProvider); ok {
+			maps.Copy(metadata, p.Metadata())
+		}
+	}
+	maps.Copy(metadata, a.opts.metadata)
+
 	instance := &registry.ServiceInstance{
 		ID:        a.opts.id,
 		Name:      a.opts.name,
 		Version:   a.opts.version,
-		Metadata:  a.opts.metadata,
+		Metadata:  metadata,
 		Endpoints: endpoints,
 	}
 	a.mu.Lock()
@@ -280,8 +280,10 @@ func (x *OMessage) GetPayload() []byte {
 
 type Code struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Code          int32                  `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"` // 消息结果code, 0: 成功, 其它: 失败
-	Tip           string                 `protobuf:"bytes,2,opt,name=tip,proto3" json:"tip,omitempty"`    // 提示信息
+	Code          int32                  `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"`                                                                                  // 消息结果code, 0: 成功, 其它: 失败
+	Tip           string                 `protobuf:"bytes,2,opt,name=tip,proto3" json:"tip,omitempty"`                                                                                     // 提示信息
+	Reason        string                 `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`                                                                               // 错误原因, 机器可读的错误标识, 如: ROOM_NOT_FOUND
+	Metadata      map[string]string      `protobuf:"bytes,4,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // 错误附加信息
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -330,6 +332,66 @@ func (x *Code) GetTip() string {
 	return ""
 }
 
+func (x *Code) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *Code) GetMetadata() map[string]string {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+// 批量输出消息
+// 客户端在握手时声明支持批量后, gate 下发的每一帧均为 OBatch
+type OBatch struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Messages      []*OMessage            `protobuf:"bytes,1,rep,name=messages,proto3" json:"messages,omitempty"` // 按下发顺序排列的输出消息
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OBatch) Reset() {
+	*x = OBatch{}
+	mi := &file_envelope_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OBatch) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OBatch) ProtoMessage() {}
+
+func (x *OBatch) ProtoReflect() protoreflect.Message {
+	mi := &file_envelope_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OBatch.ProtoReflect.Descriptor instead.
+func (*OBatch) Descriptor() ([]byte, []int) {
+	return file_envelope_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *OBatch) GetMessages() []*OMessage {
+	if x != nil {
+		return x.Messages
+	}
+	return nil
+}
+
 var File_envelope_proto protoreflect.FileDescriptor
 
 const file_envelope_proto_rawDesc = "" +
@@ -349,10 +411,17 @@ const file_envelope_proto_rawDesc = "" +
 	"\aservice\x18\x02 \x01(\tR\aservice\x12,\n" +
 	"\bmsg_type\x18\x03 \x01(\x0e2\x11.envelope.MsgTypeR\amsgType\x12&\n" +
 	"\x06result\x18\x04 \x01(\v2\x0e.envelope.CodeR\x06result\x12\x18\n" +
-	"\apayload\x18\x05 \x01(\fR\apayload\",\n" +
+	"\apayload\x18\x05 \x01(\fR\apayload\"\xbb\x01\n" +
 	"\x04Code\x12\x12\n" +
 	"\x04code\x18\x01 \x01(\x05R\x04code\x12\x10\n" +
-	"\x03tip\x18\x02 \x01(\tR\x03tip*.\n" +
+	"\x03tip\x18\x02 \x01(\tR\x03tip\x12\x16\n" +
+	"\x06reason\x18\x03 \x01(\tR\x06reason\x128\n" +
+	"\bmetadata\x18\x04 \x03(\v2\x1c.envelope.Code.MetadataEntryR\bmetadata\x1a;\n" +
+	"\rMetadataEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"8\n" +
+	"\x06OBatch\x12.\n" +
+	"\bmessages\x18\x01 \x03(\v2\x12.envelope.OMessageR\bmessages*.\n" +
 	"\aMsgType\x12\v\n" +
 	"\aUNKNOWN\x10\x00\x12\f\n" +
 	"\bRESPONSE\x10\x01\x12\b\n" +
@@ -371,24 +440,28 @@ func file_envelope_proto_rawDescGZIP() []byte {
 }
 
 var file_envelope_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_envelope_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_envelope_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
 var file_envelope_proto_goTypes = []any{
 	(MsgType)(0),     // 0: envelope.MsgType
 	(*Header)(nil),   // 1: envelope.Header
 	(*IMessage)(nil), // 2: envelope.IMessage
 	(*OMessage)(nil), // 3: envelope.OMessage
 	(*Code)(nil),     // 4: envelope.Code
+	(*OBatch)(nil),   // 5: envelope.OBatch
+	nil,              // 6: envelope.Code.MetadataEntry
 }
 var file_envelope_proto_depIdxs = []int32{
 	1, // 0: envelope.IMessage.header:type_name -> envelope.Header
 	1, // 1: envelope.OMessage.header:type_name -> envelope.Header
 	0, // 2: envelope.OMessage.msg_type:type_name -> envelope.MsgType
 	4, // 3: envelope.OMessage.result:type_name -> envelope.Code
-	4, // [4:4] is the sub-list for method output_type
-	4, // [4:4] is the sub-list for method input_type
-	4, // [4:4] is the sub-list for extension type_name
-	4, // [4:4] is the sub-list for extension extendee
-	0, // [0:4] is the sub-list for field type_name
+	6, // 4: envelope.Code.metadata:type_name -> envelope.Code.MetadataEntry
+	3, // 5: envelope.OBatch.messages:type_name -> envelope.OMessage
+	6, // [6:6] is the sub-list for method output_type
+	6, // [6:6] is the sub-list for method input_type
+	6, // [6:6] is the sub-list for extension type_name
+	6, // [6:6] is the sub-list for extension extendee
+	0, // [0:6] is the sub-list for field type_name
 }
 
 func init() { file_envelope_proto_init() }
@@ -402,7 +475,7 @@ func file_envelope_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_envelope_proto_rawDesc), len(file_envelope_proto_rawDesc)),
 			NumEnums:      1,
-			NumMessages:   4,
+			NumMessages:   6,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
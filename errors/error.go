@@ -0,0 +1,224 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+)
+
+const (
+	// UnknownCode 未知错误的状态码
+	UnknownCode = http.StatusInternalServerError
+	// UnknownReason 未知错误的原因
+	UnknownReason = ""
+)
+
+// Error 跨服务传递的结构化错误
+//   - Code: 状态码, 与 http 状态码语义一致, 200 表示成功
+//   - Reason: 错误原因, 机器可读的错误标识, 如: ROOM_NOT_FOUND, 用于错误判定
+//   - Message: 提示信息, 面向用户
+//   - Metadata: 附加信息
+type Error struct {
+	Code     int
+	Reason   string
+	Message  string
+	Metadata map[string]string
+
+	cause error
+}
+
+// New 创建结构化错误
+func New(code int, reason, message string) *Error {
+	return &Error{
+		Code:    code,
+		Reason:  reason,
+		Message: message,
+	}
+}
+
+// Newf 创建结构化错误, message 按 format 格式化
+func Newf(code int, reason, format string, a ...any) *Error {
+	return New(code, reason, fmt.Sprintf(format, a...))
+}
+
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return fmt.Sprintf("error: code = %d reason = %s message = %s metadata = %v cause = %v", e.Code, e.Reason, e.Message, e.Metadata, e.cause)
+	}
+	return fmt.Sprintf("error: code = %d reason = %s message = %s metadata = %v", e.Code, e.Reason, e.Message, e.Metadata)
+}
+
+// Unwrap 返回底层错误
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Is 错误原因相同时视为同一错误, 目标错误原因为空时按状态码判定, 供 errors.Is 使用
+// 示例: errors.Is(err, errors.NotFound("ROOM_NOT_FOUND", "")) 或 errors.Is(err, errors.NotFound("", ""))
+func (e *Error) Is(target error) bool {
+	var t *Error
+	if !errors.As(target, &t) {
+		return false
+	}
+	if t.Reason != "" {
+		return t.Reason == e.Reason
+	}
+	return t.Code == e.Code
+}
+
+// WithCause 返回附带底层错误的副本
+func (e *Error) WithCause(cause error) *Error {
+	err := e.clone()
+	err.cause = cause
+	return err
+}
+
+// WithMetadata 返回附带附加信息的副本
+func (e *Error) WithMetadata(md map[string]string) *Error {
+	err := e.clone()
+	err.Metadata = maps.Clone(md)
+	return err
+}
+
+func (e *Error) clone() *Error {
+	if e == nil {
+		return nil
+	}
+	return &Error{
+		Code:     e.Code,
+		Reason:   e.Reason,
+		Message:  e.Message,
+		Metadata: maps.Clone(e.Metadata),
+		cause:    e.cause,
+	}
+}
+
+// Code 返回错误的状态码, nil 返回 200
+func Code(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	return FromError(err).Code
+}
+
+// Reason 返回错误原因, 非结构化错误返回 UnknownReason
+func Reason(err error) string {
+	if err == nil {
+		return UnknownReason
+	}
+	return FromError(err).Reason
+}
+
+// FromError 将任意错误转换为结构化错误
+// 错误链中包含 *Error 时返回该错误, 否则包装为 UnknownCode 错误; nil 返回 nil
+func FromError(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return New(UnknownCode, UnknownReason, err.Error()).WithCause(err)
+}
+
+// Is 同标准库 errors.Is
+func Is(err, target error) bool {
+	return errors.Is(err, target)
+}
+
+// As 同标准库 errors.As
+func As(err error, target any) bool {
+	return errors.As(err, target)
+}
+
+// BadRequest 400 请求参数错误
+func BadRequest(reason, message string) *Error {
+	return New(http.StatusBadRequest, reason, message)
+}
+
+// IsBadRequest 是否为 400 错误
+func IsBadRequest(err error) bool {
+	return Code(err) == http.StatusBadRequest
+}
+
+// Unauthorized 401 未认证
+func Unauthorized(reason, message string) *Error {
+	return New(http.StatusUnauthorized, reason, message)
+}
+
+// IsUnauthorized 是否为 401 错误
+func IsUnauthorized(err error) bool {
+	return Code(err) == http.StatusUnauthorized
+}
+
+// Forbidden 403 无权限
+func Forbidden(reason, message string) *Error {
+	return New(http.StatusForbidden, reason, message)
+}
+
+// IsForbidden 是否为 403 错误
+func IsForbidden(err error) bool {
+	return Code(err) == http.StatusForbidden
+}
+
+// NotFound 404 资源不存在
+func NotFound(reason, message string) *Error {
+	return New(http.StatusNotFound, reason, message)
+}
+
+// IsNotFound 是否为 404 错误
+func IsNotFound(err error) bool {
+	return Code(err) == http.StatusNotFound
+}
+
+// Conflict 409 状态冲突
+func Conflict(reason, message string) *Error {
+	return New(http.StatusConflict, reason, message)
+}
+
+// IsConflict 是否为 409 错误
+func IsConflict(err error) bool {
+	return Code(err) == http.StatusConflict
+}
+
+// TooManyRequests 429 请求过于频繁
+func TooManyRequests(reason, message string) *Error {
+	return New(http.StatusTooManyRequests, reason, message)
+}
+
+// IsTooManyRequests 是否为 429 错误
+func IsTooManyRequests(err error) bool {
+	return Code(err) == http.StatusTooManyRequests
+}
+
+// InternalServer 500 服务内部错误
+func InternalServer(reason, message string) *Error {
+	return New(http.StatusInternalServerError, reason, message)
+}
+
+// IsInternalServer 是否为 500 错误
+func IsInternalServer(err error) bool {
+	return Code(err) == http.StatusInternalServerError
+}
+
+// ServiceUnavailable 503 服务不可用
+func ServiceUnavailable(reason, message string) *Error {
+	return New(http.StatusServiceUnavailable, reason, message)
+}
+
+// IsServiceUnavailable 是否为 503 错误
+func IsServiceUnavailable(err error) bool {
+	return Code(err) == http.StatusServiceUnavailable
+}
+
+// GatewayTimeout 504 调用超时
+func GatewayTimeout(reason, message string) *Error {
+	return New(http.StatusGatewayTimeout, reason, message)
+}
+
+// IsGatewayTimeout 是否为 504 错误
+func IsGatewayTimeout(err error) bool {
+	return Code(err) == http.StatusGatewayTimeout
+}
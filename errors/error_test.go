@@ -0,0 +1,82 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+)
+
+func TestErrorIs(t *testing.T) {
+	err := fmt.Errorf("find room: %w", NotFound("ROOM_NOT_FOUND", "room not found"))
+	if !Is(err, NotFound("ROOM_NOT_FOUND", "")) {
+		t.Fatal("should match by reason")
+	}
+	if !Is(err, InternalServer("ROOM_NOT_FOUND", "")) {
+		t.Fatal("reason match should ignore code")
+	}
+	if Is(err, NotFound("SEAT_NOT_FOUND", "")) {
+		t.Fatal("different reason should not match")
+	}
+	if !Is(err, NotFound("", "")) || Is(err, Forbidden("", "")) {
+		t.Fatal("empty reason should match by code")
+	}
+	if Is(err, io.EOF) {
+		t.Fatal("non structured target should not match")
+	}
+}
+
+func TestErrorAs(t *testing.T) {
+	err := fmt.Errorf("wrap: %w", Conflict("SEAT_TAKEN", "seat taken"))
+	var e *Error
+	if !As(err, &e) || e.Code != 409 || e.Reason != "SEAT_TAKEN" {
+		t.Fatalf("unexpected as result: %v", e)
+	}
+	if As(io.EOF, &e) {
+		t.Fatal("plain error should not convert")
+	}
+}
+
+func TestFromError(t *testing.T) {
+	if FromError(nil) != nil {
+		t.Fatal("nil should stay nil")
+	}
+	src := BadRequest("BAD", "bad request")
+	if FromError(fmt.Errorf("wrap: %w", src)) != src {
+		t.Fatal("structured error in chain should be returned as is")
+	}
+	e := FromError(io.EOF)
+	if e.Code != UnknownCode || e.Reason != UnknownReason || e.Message != io.EOF.Error() || !errors.Is(e, io.EOF) {
+		t.Fatalf("unexpected unknown error: %v", e)
+	}
+	if Code(nil) != 200 || Code(io.EOF) != UnknownCode || Reason(src) != "BAD" {
+		t.Fatal("unexpected code or reason")
+	}
+}
+
+func TestWithCause(t *testing.T) {
+	src := ServiceUnavailable("UNAVAILABLE", "service unavailable")
+	e := src.WithCause(io.EOF)
+	if src.Unwrap() != nil {
+		t.Fatal("WithCause should not modify the original")
+	}
+	if !errors.Is(e, io.EOF) || !Is(e, src) || e.Message != src.Message {
+		t.Fatalf("unexpected error: %v", e)
+	}
+}
+
+func TestWithMetadata(t *testing.T) {
+	md := map[string]string{"room": "7"}
+	src := NotFound("ROOM_NOT_FOUND", "room not found")
+	e := src.WithMetadata(md)
+	md["room"] = "8"
+	if src.Metadata != nil {
+		t.Fatal("WithMetadata should not modify the original")
+	}
+	if e.Metadata["room"] != "7" {
+		t.Fatalf("metadata should be copied: %v", e.Metadata)
+	}
+	if c := e.WithCause(io.EOF); c.Metadata["room"] != "7" {
+		t.Fatal("metadata should survive WithCause")
+	}
+}
@@ -9,4 +9,5 @@ var (
 	ErrBrokerRequired    = errors.New("broker required")
 	ErrDiscoveryRequired = errors.New("discovery required")
 	ErrSelectorRequired  = errors.New("selector func required")
+	ErrMigratorRequired  = errors.New("migrator required")
 )
@@ -1,6 +1,8 @@
 package internal
 
 import (
+	"context"
+
 	goredis "github.com/redis/go-redis/v9"
 
 	"github.com/byteweap/meta/contrib/broker/nats"
@@ -9,6 +11,7 @@ import (
 	"github.com/byteweap/meta/examples/game/internal/handler/rpc"
 	"github.com/byteweap/meta/examples/game/internal/server"
 	"github.com/byteweap/meta/server/mesh"
+	"github.com/byteweap/meta/server/mesh/actor"
 )
 
 func New() (*server.Server, func(), error) {
@@ -30,12 +33,18 @@ func New() (*server.Server, func(), error) {
 	)
 
 	e := event.New(g)
-	g.Route(1, 1, mesh.Wrap(e.EnterGame))
+	g.Route(1, 1, mesh.Wrap(actor.Route(g.Players().System, actor.ByUid, e.EnterGame)))
 	g.Route(2, 1, mesh.Wrap(e.ExitGame))
 
 	r := rpc.New(g)
 	g.RpcRoute("hello", "v1", mesh.WrapRpc(r.Hello))
 
+	// 处理完缓冲消息后关闭 actor 系统
+	g.ShutdownHandler(func(context.Context) {
+		g.Players().Shutdown()
+		g.Rooms().Shutdown()
+	})
+
 	return g, func() {
 		_ = loc.Close()
 		_ = bro.Close()
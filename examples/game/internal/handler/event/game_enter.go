@@ -4,10 +4,11 @@ import (
 	"github.com/byteweap/meta/component/log"
 	"github.com/byteweap/meta/examples/game/internal/pb"
 	"github.com/byteweap/meta/server/mesh"
+	"github.com/byteweap/meta/server/mesh/actor"
 )
 
-// EnterGame 进入游戏
-func (h *EventHandler) EnterGame(ctx *mesh.Context, req *pb.EnterGameRequest) {
+// EnterGame 进入游戏, 在玩家 actor 中执行
+func (h *EventHandler) EnterGame(a *actor.Context, ctx *mesh.Context, req *pb.EnterGameRequest) {
 	log.Infof("EnterGame, ctx: %v, req: %v", ctx, req.String())
 	ctx.OkResp()
 }
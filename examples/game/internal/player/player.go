@@ -1,5 +1,12 @@
 package player
 
+import (
+	"github.com/byteweap/meta/server/mesh/actor"
+)
+
+var _ actor.Actor = (*Player)(nil)
+
+// Player 玩家 actor, 状态仅在 actor goroutine 中访问, 无需加锁
 type Player struct {
 	id int64
 }
@@ -13,3 +20,7 @@ func New(id int64) *Player {
 func (p *Player) ID() int64 {
 	return p.id
 }
+
+// Receive 处理投递给玩家的消息
+func (p *Player) Receive(ctx *actor.Context, msg any) {
+}
@@ -1,11 +1,22 @@
 package room
 
+import (
+	"github.com/byteweap/meta/server/mesh/actor"
+)
+
+var _ actor.Actor = (*Room)(nil)
+
+// Room 房间 actor, 状态仅在 actor goroutine 中访问, 无需加锁
 type Room struct {
-	id int
+	id int64
 }
 
-func New(id int) *Room {
+func New(id int64) *Room {
 	return &Room{
 		id: id,
 	}
 }
+
+// Receive 处理投递给房间的消息
+func (r *Room) Receive(ctx *actor.Context, msg any) {
+}
@@ -1,42 +1,25 @@
 package room
 
 import (
-	"sync"
-	"sync/atomic"
+	"time"
+
+	"github.com/byteweap/meta/server/mesh/actor"
 )
 
-// Space 房间空间
+// Space 房间空间, 每个房间对应一个 actor
 type Space struct {
-	num   atomic.Int32 // 房间数量
-	rooms sync.Map     // 房间集合 key: 房间ID value: *Room
+	*actor.System
 }
 
 func NewSpace() *Space {
-	return &Space{}
-}
-
-// GetRoom 获取房间
-func (s *Space) GetRoom(id int) (*Room, bool) {
-	v, ok := s.rooms.Load(id)
-	if !ok {
-		return nil, false
+	return &Space{
+		System: actor.NewSystem(func(id int64) actor.Actor {
+			return New(id)
+		}, actor.IdleTimeout(30*time.Minute)),
 	}
-	return v.(*Room), true
-}
-
-// Register 注册房间
-func (s *Space) Register(r *Room) {
-	s.num.Add(1)
-	s.rooms.Store(r.id, r)
-}
-
-// Unregister 注销房间
-func (s *Space) Unregister(id int) {
-	s.num.Add(-1)
-	s.rooms.Delete(id)
 }
 
 // NumRooms 获取房间数量
 func (s *Space) NumRooms() int {
-	return int(s.num.Load())
+	return s.Len()
 }
@@ -22,3 +22,13 @@ func New(opts ...mesh.Option) *Server {
 		playerSpace: player.NewSpace(),
 	}
 }
+
+// Players 玩家空间
+func (g *Server) Players() *player.Space {
+	return g.playerSpace
+}
+
+// Rooms 房间空间
+func (g *Server) Rooms() *room.Space {
+	return g.roomSpace
+}
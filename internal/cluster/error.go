@@ -0,0 +1,60 @@
+package cluster
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/byteweap/meta/component/broker"
+	es "github.com/byteweap/meta/errors"
+	"github.com/byteweap/meta/pkg/conv"
+)
+
+const (
+	FieldName_Code   = "code"
+	FieldName_Tip    = "tip"
+	FieldName_Reason = "reason"
+
+	errorMetadataPrefix = "err-md-" // 错误附加信息请求头前缀
+)
+
+// SetError 将结构化错误写入消息头, 覆盖消息头中已有的错误字段
+func SetError(header broker.Header, e *es.Error) {
+	ClearError(header)
+	header.Set(FieldName_Code, conv.String(e.Code))
+	header.Set(FieldName_Tip, e.Message)
+	if e.Reason != "" {
+		header.Set(FieldName_Reason, e.Reason)
+	}
+	for k, v := range e.Metadata {
+		header.Set(errorMetadataPrefix+k, v)
+	}
+}
+
+// ClearError 移除消息头中的状态码、提示信息、错误原因与附加信息
+// 复用请求消息头回复时, 避免将请求中残留的错误字段带给调用方
+func ClearError(header broker.Header) {
+	for k := range header {
+		if k == FieldName_Code || k == FieldName_Tip || k == FieldName_Reason || strings.HasPrefix(k, errorMetadataPrefix) {
+			delete(header, k)
+		}
+	}
+}
+
+// GetErrorBy 从消息头中解析结构化错误, 状态码为空或 200 时返回 nil
+func GetErrorBy(header broker.Header) *es.Error {
+	code := conv.Int(header.Get(FieldName_Code))
+	if code == 0 || code == http.StatusOK {
+		return nil
+	}
+	e := es.New(code, header.Get(FieldName_Reason), header.Get(FieldName_Tip))
+	for k, v := range header {
+		if len(v) == 0 || !strings.HasPrefix(k, errorMetadataPrefix) {
+			continue
+		}
+		if e.Metadata == nil {
+			e.Metadata = make(map[string]string)
+		}
+		e.Metadata[strings.TrimPrefix(k, errorMetadataPrefix)] = v[0]
+	}
+	return e
+}
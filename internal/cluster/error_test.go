@@ -0,0 +1,26 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/byteweap/meta/component/broker"
+	es "github.com/byteweap/meta/errors"
+)
+
+func TestErrorHeaderRoundTrip(t *testing.T) {
+	header := broker.Header{}
+	SetError(header, es.NotFound("ROOM_NOT_FOUND", "room not found").WithMetadata(map[string]string{"room": "7"}))
+
+	e := GetErrorBy(header)
+	if e == nil || e.Code != 404 || e.Reason != "ROOM_NOT_FOUND" || e.Message != "room not found" || e.Metadata["room"] != "7" {
+		t.Fatalf("unexpected error: %v", e)
+	}
+	if !es.Is(e, es.NotFound("ROOM_NOT_FOUND", "")) || es.Is(e, es.NotFound("SEAT_NOT_FOUND", "")) {
+		t.Fatalf("errors.Is should match by reason")
+	}
+
+	header.Set(FieldName_Code, "200")
+	if GetErrorBy(header) != nil {
+		t.Fatalf("status 200 should not be an error")
+	}
+}
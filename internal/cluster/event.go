@@ -7,4 +7,23 @@ const (
 	Event_Online    Event = "online"    // 上线
 	Event_Offline   Event = "offline"   // 掉线
 	Event_Reconnect Event = "reconnect" // 重连
+	Event_Kicked    Event = "kicked"    // 被踢下线
+	Event_Bind      Event = "bind"      // 绑定到节点(本地事件)
+	Event_Unbind    Event = "unbind"    // 解除绑定(本地事件)
+
+	// 玩家迁移(request-reply)
+	Event_Pause   Event = "pause"   // 网关暂停转发玩家发往某服务的消息
+	Event_Resume  Event = "resume"  // 网关恢复转发并投递暂停期间缓存的消息
+	Event_Migrate Event = "migrate" // 目标节点导入玩家状态
 )
+
+// IsSystemEvent 是否为系统事件(含业务消息、生命周期与迁移事件), 系统事件仅由框架发布
+func IsSystemEvent(event Event) bool {
+	switch event {
+	case "", Event_Business,
+		Event_Online, Event_Offline, Event_Reconnect, Event_Kicked, Event_Bind, Event_Unbind,
+		Event_Pause, Event_Resume, Event_Migrate:
+		return true
+	}
+	return false
+}
@@ -11,6 +11,7 @@ const (
 	FieldName_Reply       = "reply"
 	FieldName_FromService = "from_service"
 	FieldName_ToService   = "to_service"
+	FieldName_FromNode    = "from_node"
 )
 
 // BuildHeader 构建必备请求头
@@ -48,3 +49,13 @@ func GetFromServiceBy(header broker.Header) string {
 func GetToServiceBy(header broker.Header) string {
 	return header.Get(FieldName_ToService)
 }
+
+// SetFromNode 设置来源节点 ID
+func SetFromNode(header broker.Header, node string) {
+	header.Set(FieldName_FromNode, node)
+}
+
+// GetFromNodeBy 从请求头中获取来源节点 ID
+func GetFromNodeBy(header broker.Header) string {
+	return header.Get(FieldName_FromNode)
+}
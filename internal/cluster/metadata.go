@@ -0,0 +1,61 @@
+package cluster
+
+import (
+	"strings"
+	"time"
+
+	"github.com/byteweap/meta/component/broker"
+	"github.com/byteweap/meta/metadata"
+	"github.com/byteweap/meta/pkg/conv"
+)
+
+const (
+	FieldName_Deadline = "deadline" // 调用方截止时间(UnixMilli)
+
+	metadataPrefix = "md-" // 元数据请求头前缀
+)
+
+// SetDeadline 将截止时间写入消息头
+func SetDeadline(header broker.Header, deadline time.Time) {
+	header.Set(FieldName_Deadline, conv.String(deadline.UnixMilli()))
+}
+
+// GetDeadlineBy 从消息头中获取截止时间
+func GetDeadlineBy(header broker.Header) (time.Time, bool) {
+	ms := conv.Int64(header.Get(FieldName_Deadline))
+	if ms <= 0 {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(ms), true
+}
+
+// SetMetadata 将元数据写入消息头
+func SetMetadata(header broker.Header, md metadata.Metadata) {
+	for k, v := range md {
+		header.Set(metadataPrefix+k, v)
+	}
+}
+
+// GetMetadataBy 从消息头中获取元数据, 不存在时返回 nil
+func GetMetadataBy(header broker.Header) metadata.Metadata {
+	var md metadata.Metadata
+	for k, v := range header {
+		if len(v) == 0 || !strings.HasPrefix(k, metadataPrefix) {
+			continue
+		}
+		if md == nil {
+			md = metadata.Metadata{}
+		}
+		md.Set(strings.TrimPrefix(k, metadataPrefix), v[0])
+	}
+	return md
+}
+
+// ClearPropagation 移除消息头中调用方透传的截止时间与元数据, 复用请求消息头回复时调用
+func ClearPropagation(header broker.Header) {
+	for k := range header {
+		if k == FieldName_Deadline || strings.HasPrefix(k, metadataPrefix) {
+			delete(header, k)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package cluster
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/byteweap/meta/component/selector"
+)
+
+const (
+	// MetadataKey_Routes 服务实例元数据中 pub-sub 路由摘要的 key, 超出 MaxRouteDigestSize 的部分依次存放于
+	// meta-routes-1、meta-routes-2 ...; 带框架前缀避免与应用元数据冲突, 且只使用 Consul 元数据 key 允许的字符
+	MetadataKey_Routes = "meta-routes"
+	// MaxRouteDigestSize 单个元数据值的最大长度(Consul 限制为 512 字节)
+	MaxRouteDigestSize = 512
+)
+
+// RouteDigest 生成 pub-sub 路由摘要
+// 格式: 以逗号分隔的 cmd.version, 按 cmd、version 升序, 如 "1.1,2.1,1001.2";
+// fallbacks 为开启版本回退的 cmd 及其最低版本, 以 cmd.version+ 表示处理不低于该版本的所有版本, 如 "1001.2+"
+func RouteDigest(routes [][2]uint32, fallbacks ...[2]uint32) string {
+	type entry struct {
+		cmd, version uint32
+		above        bool
+	}
+	entries := make([]entry, 0, len(routes)+len(fallbacks))
+	for _, r := range routes {
+		entries = append(entries, entry{cmd: r[0], version: r[1]})
+	}
+	for _, r := range fallbacks {
+		entries = append(entries, entry{cmd: r[0], version: r[1], above: true})
+	}
+	slices.SortFunc(entries, func(a, b entry) int {
+		if c := cmp.Or(cmp.Compare(a.cmd, b.cmd), cmp.Compare(a.version, b.version)); c != 0 {
+			return c
+		}
+		switch {
+		case a.above == b.above:
+			return 0
+		case a.above:
+			return 1
+		default:
+			return -1
+		}
+	})
+	var sb strings.Builder
+	for i, e := range entries {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(strconv.FormatUint(uint64(e.cmd), 10))
+		sb.WriteByte('.')
+		sb.WriteString(strconv.FormatUint(uint64(e.version), 10))
+		if e.above {
+			sb.WriteByte('+')
+		}
+	}
+	return sb.String()
+}
+
+// RouteMetadata 将路由摘要按 MaxRouteDigestSize 在条目边界处分片, 返回写入服务实例元数据的键值
+func RouteMetadata(digest string) map[string]string {
+	md := make(map[string]string, len(digest)/MaxRouteDigestSize+1)
+	for i := 0; ; i++ {
+		chunk := digest
+		if len(chunk) > MaxRouteDigestSize {
+			chunk = chunk[:MaxRouteDigestSize+1]
+			chunk = chunk[:max(strings.LastIndexByte(chunk, ','), 0)]
+		}
+		md[routeMetadataKey(i)] = chunk
+		digest = strings.TrimPrefix(digest[len(chunk):], ",")
+		if digest == "" || chunk == "" {
+			return md
+		}
+	}
+}
+
+func routeMetadataKey(i int) string {
+	if i == 0 {
+		return MetadataKey_Routes
+	}
+	return MetadataKey_Routes + "-" + strconv.Itoa(i)
+}
+
+// RouteSet 解析后的路由摘要
+type RouteSet struct {
+	exact map[uint64]struct{} // key: cmd<<32 | version
+	above map[uint32]uint32   // cmd.version+ 条目, key: cmd, value: 最低版本
+}
+
+// ParseRouteDigest 解析路由摘要, 忽略无法解析的条目
+func ParseRouteDigest(digest string) *RouteSet {
+	rs := &RouteSet{exact: make(map[uint64]struct{})}
+	for digest != "" {
+		var r string
+		r, digest, _ = strings.Cut(digest, ",")
+		r, above := strings.CutSuffix(r, "+")
+		c, v, _ := strings.Cut(r, ".")
+		cmd, err := strconv.ParseUint(c, 10, 32)
+		if err != nil {
+			continue
+		}
+		version, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			continue
+		}
+		if !above {
+			rs.exact[cmd<<32|version] = struct{}{}
+			continue
+		}
+		if rs.above == nil {
+			rs.above = make(map[uint32]uint32)
+		}
+		if lowest, ok := rs.above[uint32(cmd)]; !ok || uint32(version) < lowest {
+			rs.above[uint32(cmd)] = uint32(version)
+		}
+	}
+	return rs
+}
+
+// ParseRouteMetadata 从服务实例元数据中解析路由摘要(含分片), 未发布摘要时返回 false
+func ParseRouteMetadata(md map[string]string) (*RouteSet, bool) {
+	digest, ok := md[MetadataKey_Routes]
+	if !ok {
+		return nil, false
+	}
+	for i := 1; ; i++ {
+		chunk, ok := md[routeMetadataKey(i)]
+		if !ok {
+			break
+		}
+		digest += "," + chunk
+	}
+	return ParseRouteDigest(digest), true
+}
+
+// Has 判断是否包含 cmd/version, cmd.version+ 匹配不低于该版本的所有版本
+func (rs *RouteSet) Has(cmd, version uint32) bool {
+	if _, ok := rs.exact[uint64(cmd)<<32|uint64(version)]; ok {
+		return true
+	}
+	lowest, ok := rs.above[cmd]
+	return ok && version >= lowest
+}
+
+// routeNode 携带已解析路由摘要的服务节点, 由 Selectors 在服务节点更新时创建, 避免每条消息重复解析
+type routeNode struct {
+	selector.Node
+	routes *RouteSet // nil 表示节点未发布路由摘要
+}
+
+func newRouteNode(id, name, version string, md map[string]string) selector.Node {
+	n := &routeNode{Node: selector.NewNode(id, name, version, md)}
+	n.routes, _ = ParseRouteMetadata(md)
+	return n
+}
+
+// RoutesOf 获取节点的路由摘要, 未发布摘要时返回 false
+// Selectors 创建的节点使用节点更新时解析的结果, 其它节点(如通过 Selectors.Store 注入)即时解析
+func RoutesOf(n selector.Node) (*RouteSet, bool) {
+	if rn, ok := n.(*routeNode); ok {
+		return rn.routes, rn.routes != nil
+	}
+	return ParseRouteMetadata(n.Meta())
+}
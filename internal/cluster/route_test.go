@@ -0,0 +1,83 @@
+package cluster
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/byteweap/meta/component/selector"
+)
+
+func TestRouteDigest(t *testing.T) {
+	digest := RouteDigest([][2]uint32{{1001, 2}, {2, 1}, {1, 1}})
+	if digest != "1.1,2.1,1001.2" {
+		t.Fatalf("unexpected digest: %s", digest)
+	}
+	rs := ParseRouteDigest(digest)
+	if !rs.Has(1001, 2) || !rs.Has(1, 1) {
+		t.Fatal("expected route in digest")
+	}
+	if rs.Has(1001, 1) || rs.Has(100, 1) || ParseRouteDigest("").Has(1, 1) {
+		t.Fatal("unexpected route in digest")
+	}
+}
+
+func TestRouteDigestFallback(t *testing.T) {
+	digest := RouteDigest([][2]uint32{{5, 3}, {5, 1}, {7, 2}}, [2]uint32{5, 1})
+	if digest != "5.1,5.1+,5.3,7.2" {
+		t.Fatalf("unexpected digest: %s", digest)
+	}
+	rs := ParseRouteDigest(digest)
+	if !rs.Has(5, 1) || !rs.Has(5, 2) || !rs.Has(5, 9) || !rs.Has(7, 2) {
+		t.Fatal("expected route in digest")
+	}
+	if rs.Has(5, 0) || rs.Has(7, 3) || rs.Has(15, 1) {
+		t.Fatal("unexpected route in digest")
+	}
+}
+
+func TestRouteMetadataSplit(t *testing.T) {
+	routes := make([][2]uint32, 0, 200)
+	for cmd := range uint32(200) {
+		routes = append(routes, [2]uint32{100000 + cmd, 1})
+	}
+	digest := RouteDigest(routes)
+	md := RouteMetadata(digest)
+	if len(md) < 2 {
+		t.Fatalf("digest of %d bytes not split: %d", len(digest), len(md))
+	}
+	parts := make([]string, 0, len(md))
+	for i := range len(md) {
+		v, ok := md[routeMetadataKey(i)]
+		if !ok || len(v) > MaxRouteDigestSize || strings.HasPrefix(v, ",") || strings.HasSuffix(v, ",") {
+			t.Fatalf("unexpected chunk %d: %q", i, v)
+		}
+		parts = append(parts, v)
+	}
+	if strings.Join(parts, ",") != digest {
+		t.Fatal("chunks do not rebuild digest")
+	}
+
+	rs, ok := ParseRouteMetadata(md)
+	if !ok || !rs.Has(100000, 1) || !rs.Has(100199, 1) || rs.Has(100200, 1) {
+		t.Fatal("unexpected routes parsed from split metadata")
+	}
+	if _, ok = ParseRouteMetadata(map[string]string{"routes": "1.1"}); ok {
+		t.Fatal("unexpected digest without meta-routes key")
+	}
+	if md = RouteMetadata(""); md[MetadataKey_Routes] != "" || len(md) != 1 {
+		t.Fatalf("unexpected metadata for empty digest: %v", md)
+	}
+}
+
+func TestRoutesOf(t *testing.T) {
+	md := map[string]string{MetadataKey_Routes: "1.1"}
+	for _, n := range []selector.Node{newRouteNode("game-1", "game", "v1", md), selector.NewNode("game-1", "game", "v1", md)} {
+		rs, ok := RoutesOf(n)
+		if !ok || !rs.Has(1, 1) || rs.Has(2, 1) {
+			t.Fatalf("unexpected routes of %T", n)
+		}
+	}
+	if _, ok := RoutesOf(newRouteNode("game-2", "game", "v1", nil)); ok {
+		t.Fatal("unexpected routes for node without digest")
+	}
+}
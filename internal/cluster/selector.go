@@ -0,0 +1,214 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/byteweap/meta/component/log"
+	"github.com/byteweap/meta/component/registry"
+	"github.com/byteweap/meta/component/selector"
+	es "github.com/byteweap/meta/errors"
+)
+
+// Selectors 服务节点选择器缓存, gate 与 mesh 共用
+// 首次访问服务时创建选择器并监听服务节点变化; 监听失败时移除选择器, 下次访问时重建
+type Selectors struct {
+	discovery   registry.Registry
+	newSelector func() selector.Selector
+
+	mu       sync.RWMutex
+	entries  map[string]*selectorEntry // key: 服务名
+	statuses map[string]*watchStatus   // 监听状态, 选择器移除后保留最近一次状态 key: 服务名
+	sfg      singleflight.Group
+}
+
+type selectorEntry struct {
+	sel     selector.Selector
+	watcher registry.Watcher // 为 nil 表示通过 Store 注入, 不监听
+}
+
+// WatchStatus 服务节点监听状态
+type WatchStatus struct {
+	Service   string
+	Running   bool
+	StartedAt time.Time
+	UpdatedAt time.Time
+	Updates   int
+	Nodes     int
+	LastError string
+}
+
+// watchStatus 服务节点监听状态, 由监听协程更新
+type watchStatus struct {
+	mu sync.Mutex
+	WatchStatus
+}
+
+// NewSelectors 创建服务节点选择器缓存
+func NewSelectors(discovery registry.Registry, newSelector func() selector.Selector) *Selectors {
+	return &Selectors{
+		discovery:   discovery,
+		newSelector: newSelector,
+		entries:     make(map[string]*selectorEntry),
+		statuses:    make(map[string]*watchStatus),
+	}
+}
+
+// Get 获取服务的选择器, 首次调用时创建并监听服务节点变化, 监听在 ctx 结束或 Stop 后停止
+func (s *Selectors) Get(ctx context.Context, service string) (selector.Selector, error) {
+	s.mu.RLock()
+	entry := s.entries[service]
+	s.mu.RUnlock()
+	if entry != nil {
+		return entry.sel, nil
+	}
+	if s.discovery == nil {
+		return nil, es.ErrDiscoveryRequired
+	}
+	if s.newSelector == nil {
+		return nil, es.ErrSelectorRequired
+	}
+
+	v, err, _ := s.sfg.Do(service, func() (any, error) {
+		s.mu.RLock()
+		if existing := s.entries[service]; existing != nil {
+			s.mu.RUnlock()
+			return existing.sel, nil
+		}
+		s.mu.RUnlock()
+
+		created := s.newSelector()
+		w, err := s.discovery.Watch(ctx, service)
+		if err != nil {
+			log.Errorf("cluster watch service error, service: %v, err: %v", service, err)
+			return nil, err
+		}
+
+		s.mu.Lock()
+		if existing := s.entries[service]; existing != nil {
+			s.mu.Unlock()
+			if stopErr := w.Stop(); stopErr != nil {
+				log.Errorf("cluster stop redundant watcher error, service: %v, err: %v", service, stopErr)
+			}
+			return existing.sel, nil
+		}
+		entry := &selectorEntry{sel: created, watcher: w}
+		status := &watchStatus{WatchStatus: WatchStatus{Service: service, Running: true, StartedAt: time.Now()}}
+		s.entries[service] = entry
+		s.statuses[service] = status
+		s.mu.Unlock()
+
+		go s.watch(ctx, service, entry, status)
+		return created, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(selector.Selector), nil
+}
+
+// watch 监听服务节点变化并更新选择器, 监听失败时移除选择器以便下次重建
+func (s *Selectors) watch(ctx context.Context, service string, entry *selectorEntry, status *watchStatus) {
+	defer func() {
+		status.mu.Lock()
+		status.Running = false
+		status.mu.Unlock()
+	}()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+			instances, err := entry.watcher.Next()
+			if err != nil {
+				log.Errorf("cluster watch service error, service: %v, err: %v", service, err)
+				status.mu.Lock()
+				status.LastError = err.Error()
+				status.mu.Unlock()
+				s.mu.Lock()
+				if s.entries[service] == entry {
+					delete(s.entries, service)
+				}
+				s.mu.Unlock()
+				return
+			}
+			nodes := make([]selector.Node, 0, len(instances))
+			for _, instance := range instances {
+				nodes = append(nodes,
+					newRouteNode(
+						instance.ID,
+						instance.Name,
+						instance.Version,
+						instance.Metadata,
+					),
+				)
+			}
+			entry.sel.Update(nodes) // 更新节点
+			status.mu.Lock()
+			status.UpdatedAt = time.Now()
+			status.Updates++
+			status.Nodes = len(nodes)
+			status.mu.Unlock()
+		}
+	}
+}
+
+// Store 注入服务的选择器(如静态节点), 不监听服务节点变化
+func (s *Selectors) Store(service string, sel selector.Selector) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[service] = &selectorEntry{sel: sel}
+}
+
+// Range 遍历已创建的选择器, fn 返回 false 时停止
+func (s *Selectors) Range(fn func(service string, sel selector.Selector) bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for service, entry := range s.entries {
+		if !fn(service, entry.sel) {
+			return
+		}
+	}
+}
+
+// Watching 服务是否正在监听
+func (s *Selectors) Watching(service string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	entry := s.entries[service]
+	return entry != nil && entry.watcher != nil
+}
+
+// Statuses 返回各服务的节点监听状态
+func (s *Selectors) Statuses() []WatchStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	statuses := make([]WatchStatus, 0, len(s.statuses))
+	for _, status := range s.statuses {
+		status.mu.Lock()
+		statuses = append(statuses, status.WatchStatus)
+		status.mu.Unlock()
+	}
+	return statuses
+}
+
+// Stop 停止所有服务节点监听并清空选择器
+func (s *Selectors) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var err error
+	for _, entry := range s.entries {
+		if entry.watcher == nil {
+			continue
+		}
+		if e := entry.watcher.Stop(); e != nil {
+			err = errors.Join(err, e)
+		}
+	}
+	clear(s.entries)
+	return err
+}
@@ -0,0 +1,123 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/byteweap/meta/component/registry"
+	"github.com/byteweap/meta/component/selector"
+	es "github.com/byteweap/meta/errors"
+)
+
+type stubWatcher struct {
+	next chan []*registry.ServiceInstance // 关闭时 Next 返回错误
+}
+
+func (w *stubWatcher) Next() ([]*registry.ServiceInstance, error) {
+	instances, ok := <-w.next
+	if !ok {
+		return nil, errors.New("watcher closed")
+	}
+	return instances, nil
+}
+
+func (w *stubWatcher) Stop() error { return nil }
+
+type stubRegistry struct {
+	registry.Registry
+	mu       sync.Mutex
+	watchers []*stubWatcher
+}
+
+func (r *stubRegistry) Watch(context.Context, string) (registry.Watcher, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	w := &stubWatcher{next: make(chan []*registry.ServiceInstance)}
+	r.watchers = append(r.watchers, w)
+	return w, nil
+}
+
+func (r *stubRegistry) watcher(i int) *stubWatcher {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.watchers[i]
+}
+
+type stubSelector struct {
+	mu    sync.Mutex
+	nodes []selector.Node
+}
+
+func (s *stubSelector) Select(string, ...selector.Filter) (selector.Node, error) {
+	return nil, selector.ErrNoAvailableNode
+}
+
+func (s *stubSelector) Update(nodes []selector.Node) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nodes = nodes
+}
+
+func (s *stubSelector) Nodes() []selector.Node {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.nodes
+}
+
+func TestSelectorsRequireComponents(t *testing.T) {
+	if _, err := NewSelectors(nil, nil).Get(context.Background(), "game"); !errors.Is(err, es.ErrDiscoveryRequired) {
+		t.Fatalf("expected discovery required, got %v", err)
+	}
+	if _, err := NewSelectors(&stubRegistry{}, nil).Get(context.Background(), "game"); !errors.Is(err, es.ErrSelectorRequired) {
+		t.Fatalf("expected selector required, got %v", err)
+	}
+}
+
+func TestSelectorsWatchAndRebuild(t *testing.T) {
+	reg := &stubRegistry{}
+	s := NewSelectors(reg, func() selector.Selector { return &stubSelector{} })
+	defer s.Stop()
+
+	sel, err := s.Get(context.Background(), "game")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if again, _ := s.Get(context.Background(), "game"); again != sel {
+		t.Fatal("selector should be cached")
+	}
+	reg.watcher(0).next <- []*registry.ServiceInstance{{ID: "game-1", Name: "game"}}
+	waitFor(t, func() bool {
+		st := s.Statuses()
+		return len(st) == 1 && st[0].Nodes == 1 && st[0].Updates == 1
+	})
+	if n := sel.Nodes(); len(n) != 1 || n[0].ID() != "game-1" {
+		t.Fatalf("unexpected nodes: %v", n)
+	}
+
+	// 监听失败后移除选择器, 保留状态, 下次访问时重建
+	close(reg.watcher(0).next)
+	waitFor(t, func() bool { return !s.Watching("game") })
+	if st := s.Statuses(); st[0].Running || st[0].LastError != "watcher closed" {
+		t.Fatalf("unexpected status: %+v", st[0])
+	}
+	rebuilt, err := s.Get(context.Background(), "game")
+	if err != nil || rebuilt == sel {
+		t.Fatalf("selector should be rebuilt, err: %v", err)
+	}
+	s.Stop()
+	close(reg.watcher(1).next)
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -8,3 +8,13 @@ func Subject(prefix, fromApp, toApp, toAppID string) string {
 	}
 	return prefix + "." + fromApp + "." + toApp + "." + toAppID
 }
+
+// AnyNode 服务级主题的节点占位
+// 无状态服务以 Queue Group 订阅服务级主题, 由 broker 在服务的所有节点间负载均衡
+const AnyNode = "_any"
+
+// ServiceSubject 组装服务级主题
+// subject = 前缀.发送方AppName.接收方AppName._any
+func ServiceSubject(prefix, fromApp, toApp string) string {
+	return Subject(prefix, fromApp, toApp, AnyNode)
+}
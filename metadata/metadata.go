@@ -0,0 +1,68 @@
+package metadata
+
+import (
+	"context"
+	"maps"
+	"strings"
+)
+
+// Metadata 请求元数据, 随消息在 gate -> mesh -> 下游服务之间透传
+// key 统一为小写
+type Metadata map[string]string
+
+// New 按键值对创建元数据, kv 长度为奇数时忽略最后一个 key
+func New(kv ...string) Metadata {
+	md := make(Metadata, len(kv)/2)
+	for i := 0; i+1 < len(kv); i += 2 {
+		md.Set(kv[i], kv[i+1])
+	}
+	return md
+}
+
+// Get 获取 key 对应的值
+func (md Metadata) Get(key string) string {
+	return md[strings.ToLower(key)]
+}
+
+// Set 设置键值对
+func (md Metadata) Set(key, value string) {
+	if key == "" {
+		return
+	}
+	md[strings.ToLower(key)] = value
+}
+
+// Clone 复制元数据
+func (md Metadata) Clone() Metadata {
+	return maps.Clone(md)
+}
+
+// Merge 合并多个元数据, 后者覆盖前者
+func Merge(mds ...Metadata) Metadata {
+	out := Metadata{}
+	for _, md := range mds {
+		for k, v := range md {
+			out.Set(k, v)
+		}
+	}
+	return out
+}
+
+type metadataKey struct{}
+
+// NewContext 返回携带元数据的 context
+func NewContext(ctx context.Context, md Metadata) context.Context {
+	return context.WithValue(ctx, metadataKey{}, md)
+}
+
+// FromContext 获取 context 中的元数据
+func FromContext(ctx context.Context) (Metadata, bool) {
+	md, ok := ctx.Value(metadataKey{}).(Metadata)
+	return md, ok
+}
+
+// AppendToContext 在 context 已有元数据的基础上追加键值对, 不修改原元数据
+func AppendToContext(ctx context.Context, kv ...string) context.Context {
+	md, _ := FromContext(ctx)
+	return NewContext(ctx, Merge(md, New(kv...)))
+}
@@ -0,0 +1,20 @@
+package metadata
+
+import (
+	"context"
+	"testing"
+)
+
+func TestContext(t *testing.T) {
+	ctx := NewContext(context.Background(), New("Trace-Id", "t1", "odd"))
+	ctx2 := AppendToContext(ctx, "region", "cn")
+
+	md, ok := FromContext(ctx2)
+	if !ok || md.Get("trace-id") != "t1" || md.Get("REGION") != "cn" || len(md) != 2 {
+		t.Fatalf("unexpected metadata: %v", md)
+	}
+	md, _ = FromContext(ctx)
+	if md.Get("region") != "" {
+		t.Fatalf("append should not modify parent metadata")
+	}
+}
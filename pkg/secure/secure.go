@@ -0,0 +1,186 @@
+// Package secure 提供客户端与网关之间的端到端加密通道
+//
+// 握手: 双方各自生成 X25519 临时密钥对并交换公钥, 通过 ECDH 得到共享密钥,
+// 再经 HKDF-SHA256 派生出两个方向各自独立的 AES-256-GCM 密钥
+// 服务端以长期 Ed25519 身份私钥对双方临时公钥签名(见 ServerHello), 客户端预置服务端身份公钥并校验签名,
+// 防止中间人替换临时公钥
+//
+// 帧格式: seq(8字节,大端) + 密文(含16字节认证标签)
+// nonce 由 seq 派生, 接收方要求 seq 严格递增, 以此防止重放
+package secure
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"sync"
+)
+
+const (
+	// KeySize 公钥长度(X25519)
+	KeySize = 32
+	// HelloSize 服务端握手消息长度: 临时公钥 + 签名
+	HelloSize = KeySize + ed25519.SignatureSize
+	// seqSize 帧头 seq 长度
+	seqSize = 8
+)
+
+var (
+	ErrInvalidKey   = errors.New("secure: invalid public key")
+	ErrInvalidFrame = errors.New("secure: invalid frame")
+	ErrInvalidSeq   = errors.New("secure: seq must be greater than zero")
+	ErrReplay       = errors.New("secure: replayed or out-of-order frame")
+	ErrInvalidHello = errors.New("secure: invalid server hello signature")
+)
+
+var (
+	infoClient2Server = "meta secure client->server"
+	infoServer2Client = "meta secure server->client"
+	helloContext      = "meta secure server hello"
+)
+
+// GenerateKey 生成 X25519 临时密钥对
+func GenerateKey() (*ecdh.PrivateKey, error) {
+	return ecdh.X25519().GenerateKey(rand.Reader)
+}
+
+// ServerHello 服务端生成临时密钥对, 返回与客户端公钥 peer 对应的加密通道及下发给客户端的握手消息
+// 握手消息格式: 服务端临时公钥(32字节) + identity 对(客户端公钥, 服务端临时公钥)的 Ed25519 签名(64字节)
+func ServerHello(identity ed25519.PrivateKey, peer []byte) (*Channel, []byte, error) {
+	if len(identity) != ed25519.PrivateKeySize {
+		return nil, nil, ErrInvalidKey
+	}
+	priv, err := GenerateKey()
+	if err != nil {
+		return nil, nil, err
+	}
+	ch, err := NewChannel(priv, peer, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	pub := priv.PublicKey().Bytes()
+	hello := append(pub, ed25519.Sign(identity, transcript(peer, pub))...)
+	return ch, hello, nil
+}
+
+// OpenServerHello 客户端以预置的服务端身份公钥校验握手消息, 通过后返回加密通道
+// priv 为客户端握手时发送公钥对应的临时私钥
+func OpenServerHello(identity ed25519.PublicKey, priv *ecdh.PrivateKey, hello []byte) (*Channel, error) {
+	if len(identity) != ed25519.PublicKeySize || priv == nil {
+		return nil, ErrInvalidKey
+	}
+	if len(hello) != HelloSize {
+		return nil, ErrInvalidHello
+	}
+	pub, sig := hello[:KeySize], hello[KeySize:]
+	if !ed25519.Verify(identity, transcript(priv.PublicKey().Bytes(), pub), sig) {
+		return nil, ErrInvalidHello
+	}
+	return NewChannel(priv, pub, false)
+}
+
+// transcript 握手签名内容: 上下文 + 客户端公钥 + 服务端临时公钥
+func transcript(client, server []byte) []byte {
+	return bytes.Join([][]byte{[]byte(helloContext), client, server}, nil)
+}
+
+// Channel 加密通道
+// Seal 与 Open 可以并发调用, 但同一方向上的 seq 需由调用方保证有序
+type Channel struct {
+	sealer cipher.AEAD // 发送方向
+	opener cipher.AEAD // 接收方向
+
+	mu      sync.Mutex
+	lastSeq uint64 // 最近一次成功解密的对端 seq
+}
+
+// NewChannel 根据本端私钥与对端公钥创建加密通道
+// server 标识本端是否为服务端(网关), 决定两个方向密钥的使用方式
+func NewChannel(priv *ecdh.PrivateKey, peer []byte, server bool) (*Channel, error) {
+	if priv == nil || len(peer) != KeySize {
+		return nil, ErrInvalidKey
+	}
+	pub, err := ecdh.X25519().NewPublicKey(peer)
+	if err != nil {
+		return nil, ErrInvalidKey
+	}
+	secret, err := priv.ECDH(pub)
+	if err != nil {
+		return nil, ErrInvalidKey
+	}
+	c2s, err := newAEAD(secret, infoClient2Server)
+	if err != nil {
+		return nil, err
+	}
+	s2c, err := newAEAD(secret, infoServer2Client)
+	if err != nil {
+		return nil, err
+	}
+	if server {
+		return &Channel{sealer: s2c, opener: c2s}, nil
+	}
+	return &Channel{sealer: c2s, opener: s2c}, nil
+}
+
+// newAEAD 派生指定方向的 AES-256-GCM
+func newAEAD(secret []byte, info string) (cipher.AEAD, error) {
+	key, err := hkdf.Key(sha256.New, secret, nil, info, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Seal 加密明文, 返回完整帧
+// seq 必须大于 0, 且同一方向上严格递增, 否则对端会视为重放而丢弃
+func (c *Channel) Seal(seq uint64, plaintext []byte) ([]byte, error) {
+	if seq == 0 {
+		return nil, ErrInvalidSeq
+	}
+	frame := make([]byte, seqSize, seqSize+len(plaintext)+c.sealer.Overhead())
+	binary.BigEndian.PutUint64(frame, seq)
+	return c.sealer.Seal(frame, nonce(c.sealer, seq), plaintext, frame[:seqSize]), nil
+}
+
+// Open 解密帧, 返回帧 seq 与明文
+// 认证失败或 seq 不大于上一次成功解密的 seq 时返回错误
+func (c *Channel) Open(frame []byte) (uint64, []byte, error) {
+	if len(frame) < seqSize+c.opener.Overhead() {
+		return 0, nil, ErrInvalidFrame
+	}
+	seq := binary.BigEndian.Uint64(frame)
+	if seq == 0 {
+		return 0, nil, ErrInvalidSeq
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if seq <= c.lastSeq {
+		return 0, nil, ErrReplay
+	}
+	plaintext, err := c.opener.Open(nil, nonce(c.opener, seq), frame[seqSize:], frame[:seqSize])
+	if err != nil {
+		return 0, nil, ErrInvalidFrame
+	}
+	c.lastSeq = seq
+	return seq, plaintext, nil
+}
+
+// nonce 由 seq 构造 GCM nonce, 高位补零
+func nonce(aead cipher.AEAD, seq uint64) []byte {
+	n := make([]byte, aead.NonceSize())
+	binary.BigEndian.PutUint64(n[len(n)-seqSize:], seq)
+	return n
+}
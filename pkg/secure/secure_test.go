@@ -0,0 +1,153 @@
+package secure
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"testing"
+)
+
+func newPair(t *testing.T) (client, server *Channel) {
+	t.Helper()
+
+	ck, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("generate client key: %v", err)
+	}
+	sk, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("generate server key: %v", err)
+	}
+	client, err = NewChannel(ck, sk.PublicKey().Bytes(), false)
+	if err != nil {
+		t.Fatalf("new client channel: %v", err)
+	}
+	server, err = NewChannel(sk, ck.PublicKey().Bytes(), true)
+	if err != nil {
+		t.Fatalf("new server channel: %v", err)
+	}
+	return client, server
+}
+
+func TestChannelRoundTrip(t *testing.T) {
+	client, server := newPair(t)
+
+	frame, err := client.Seal(1, []byte("ping"))
+	if err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+	seq, plain, err := server.Open(frame)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if seq != 1 || !bytes.Equal(plain, []byte("ping")) {
+		t.Fatalf("unexpected result: seq=%d plain=%s", seq, plain)
+	}
+
+	frame, err = server.Seal(1, []byte("pong"))
+	if err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+	if _, plain, err = client.Open(frame); err != nil || !bytes.Equal(plain, []byte("pong")) {
+		t.Fatalf("unexpected result: plain=%s err=%v", plain, err)
+	}
+}
+
+func TestChannelRejectsReplay(t *testing.T) {
+	client, server := newPair(t)
+
+	f1, _ := client.Seal(1, []byte("a"))
+	f2, _ := client.Seal(2, []byte("b"))
+
+	if _, _, err := server.Open(f2); err != nil {
+		t.Fatalf("open f2: %v", err)
+	}
+	if _, _, err := server.Open(f2); !errors.Is(err, ErrReplay) {
+		t.Fatalf("expected replay error, got %v", err)
+	}
+	if _, _, err := server.Open(f1); !errors.Is(err, ErrReplay) {
+		t.Fatalf("expected out-of-order error, got %v", err)
+	}
+}
+
+func TestChannelRejectsTampering(t *testing.T) {
+	client, server := newPair(t)
+
+	frame, _ := client.Seal(1, []byte("payload"))
+	frame[len(frame)-1] ^= 0xff
+	if _, _, err := server.Open(frame); !errors.Is(err, ErrInvalidFrame) {
+		t.Fatalf("expected invalid frame, got %v", err)
+	}
+
+	// 篡改 seq 同样无法通过认证
+	frame, _ = client.Seal(2, []byte("payload"))
+	frame[7] = 3
+	if _, _, err := server.Open(frame); !errors.Is(err, ErrInvalidFrame) {
+		t.Fatalf("expected invalid frame, got %v", err)
+	}
+	// 密文被篡改时不会推进 seq
+	frame, _ = client.Seal(2, []byte("payload"))
+	if _, _, err := server.Open(frame); err != nil {
+		t.Fatalf("open: %v", err)
+	}
+}
+
+func TestChannelDirectionsAreIndependent(t *testing.T) {
+	client, _ := newPair(t)
+
+	// 客户端无法解密自己发送的帧
+	frame, _ := client.Seal(1, []byte("x"))
+	if _, _, err := client.Open(frame); err == nil {
+		t.Fatalf("expected error when opening own frame")
+	}
+}
+
+func TestServerHello(t *testing.T) {
+	idPub, idPriv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generate identity: %v", err)
+	}
+	ck, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("generate client key: %v", err)
+	}
+	server, hello, err := ServerHello(idPriv, ck.PublicKey().Bytes())
+	if err != nil {
+		t.Fatalf("server hello: %v", err)
+	}
+	client, err := OpenServerHello(idPub, ck, hello)
+	if err != nil {
+		t.Fatalf("open server hello: %v", err)
+	}
+	frame, _ := server.Seal(1, []byte("pong"))
+	if _, plain, err := client.Open(frame); err != nil || !bytes.Equal(plain, []byte("pong")) {
+		t.Fatalf("unexpected result: plain=%s err=%v", plain, err)
+	}
+}
+
+func TestServerHelloRejectsSubstitution(t *testing.T) {
+	idPub, idPriv, _ := ed25519.GenerateKey(nil)
+	_, mitmPriv, _ := ed25519.GenerateKey(nil)
+	ck, _ := GenerateKey()
+
+	// 中间人以自身临时公钥替换服务端公钥, 无法生成有效签名
+	_, hello, _ := ServerHello(idPriv, ck.PublicKey().Bytes())
+	mk, _ := GenerateKey()
+	forged := append(mk.PublicKey().Bytes(), hello[KeySize:]...)
+	if _, err := OpenServerHello(idPub, ck, forged); !errors.Is(err, ErrInvalidHello) {
+		t.Fatalf("expected invalid hello for substituted key, got %v", err)
+	}
+
+	// 中间人以自身身份私钥签名
+	_, hello, _ = ServerHello(mitmPriv, ck.PublicKey().Bytes())
+	if _, err := OpenServerHello(idPub, ck, hello); !errors.Is(err, ErrInvalidHello) {
+		t.Fatalf("expected invalid hello for unknown identity, got %v", err)
+	}
+
+	// 中间人替换客户端公钥后转发服务端的握手消息
+	mk2, _ := GenerateKey()
+	_, hello, _ = ServerHello(idPriv, mk2.PublicKey().Bytes())
+	if _, err := OpenServerHello(idPub, ck, hello); !errors.Is(err, ErrInvalidHello) {
+		t.Fatalf("expected invalid hello for substituted client key, got %v", err)
+	}
+}
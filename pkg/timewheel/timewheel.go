@@ -0,0 +1,219 @@
+package timewheel
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	nearBits  = 8
+	nearSize  = 1 << nearBits // 第一层槽数
+	nearMask  = nearSize - 1
+	levelBits = 6
+	levelSize = 1 << levelBits // 上层每层槽数
+	levelMask = levelSize - 1
+	levels    = 4 // 上层层数, 总范围 2^32 个 tick
+
+	maxTicks = 1<<(nearBits+levels*levelBits) - 1
+)
+
+// TimeWheel 分层时间轮
+// 第一层 256 个槽, 每槽一个 tick; 之上 4 层, 每层 64 个槽, 槽跨度逐层放大 64 倍,
+// 添加、取消定时器均为 O(1), 适合管理数十万级别的定时器.
+// 到期回调在时间轮驱动协程中同步执行, 回调应尽快返回(如投递到其它执行器)
+type TimeWheel struct {
+	tick time.Duration
+
+	mu     sync.Mutex
+	now    uint64 // 已推进的 tick 数
+	near   [nearSize]bucket
+	levels [levels][levelSize]bucket
+	size   int // 待触发定时器数量
+}
+
+// Timer 定时器
+type Timer struct {
+	tw       *TimeWheel
+	expire   uint64 // 到期 tick
+	interval uint64 // 周期 tick 数, 0 表示一次性
+	fn       func()
+
+	bucket     *bucket
+	prev, next *Timer
+}
+
+// bucket 槽, 双向链表
+type bucket struct {
+	head *Timer
+}
+
+func (b *bucket) push(t *Timer) {
+	t.bucket = b
+	t.prev = nil
+	t.next = b.head
+	if b.head != nil {
+		b.head.prev = t
+	}
+	b.head = t
+}
+
+func (b *bucket) remove(t *Timer) {
+	if t.prev != nil {
+		t.prev.next = t.next
+	} else {
+		b.head = t.next
+	}
+	if t.next != nil {
+		t.next.prev = t.prev
+	}
+	t.bucket, t.prev, t.next = nil, nil, nil
+}
+
+// take 取出槽内全部定时器
+func (b *bucket) take() *Timer {
+	head := b.head
+	b.head = nil
+	return head
+}
+
+// New 创建时间轮, tick 为最小时间精度
+func New(tick time.Duration) *TimeWheel {
+	if tick <= 0 {
+		tick = time.Millisecond
+	}
+	return &TimeWheel{tick: tick}
+}
+
+// Tick 返回时间精度
+func (tw *TimeWheel) Tick() time.Duration {
+	return tw.tick
+}
+
+// Len 返回待触发定时器数量
+func (tw *TimeWheel) Len() int {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	return tw.size
+}
+
+// AfterFunc 在 d 之后执行 fn, d 向上取整到 tick
+func (tw *TimeWheel) AfterFunc(d time.Duration, fn func()) *Timer {
+	return tw.add(tw.ticks(d), 0, fn)
+}
+
+// Every 每隔 d 执行一次 fn, 按固定频率触发, 不受回调耗时影响
+func (tw *TimeWheel) Every(d time.Duration, fn func()) *Timer {
+	n := tw.ticks(d)
+	return tw.add(n, n, fn)
+}
+
+// ticks 将时长换算为 tick 数, 至少 1 个 tick
+func (tw *TimeWheel) ticks(d time.Duration) uint64 {
+	n := uint64((d + tw.tick - 1) / tw.tick)
+	return min(max(n, 1), maxTicks)
+}
+
+func (tw *TimeWheel) add(delay, interval uint64, fn func()) *Timer {
+	t := &Timer{tw: tw, interval: interval, fn: fn}
+	tw.mu.Lock()
+	t.expire = tw.now + delay
+	tw.place(t)
+	tw.size++
+	tw.mu.Unlock()
+	return t
+}
+
+// place 按剩余 tick 数将定时器放入对应层的槽
+func (tw *TimeWheel) place(t *Timer) {
+	expire := t.expire
+	if expire < tw.now {
+		expire = tw.now
+	}
+	delta := expire - tw.now
+	if delta < nearSize {
+		tw.near[expire&nearMask].push(t)
+		return
+	}
+	for i := range levels {
+		shift := nearBits + i*levelBits
+		if delta < 1<<(shift+levelBits) || i == levels-1 {
+			tw.levels[i][(expire>>shift)&levelMask].push(t)
+			return
+		}
+	}
+}
+
+// Stop 取消定时器, 返回定时器是否仍处于待触发状态
+func (t *Timer) Stop() bool {
+	tw := t.tw
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	t.interval = 0
+	if t.bucket == nil {
+		return false
+	}
+	t.bucket.remove(t)
+	tw.size--
+	return true
+}
+
+// Run 驱动时间轮直到 ctx 结束
+func (tw *TimeWheel) Run(ctx context.Context) {
+	ticker := time.NewTicker(tw.tick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			tw.Advance()
+		}
+	}
+}
+
+// Advance 推进一个 tick 并执行到期回调
+func (tw *TimeWheel) Advance() {
+	tw.mu.Lock()
+	tw.now++
+	tw.cascade()
+	expired := tw.near[tw.now&nearMask].take()
+	var fns []func()
+	for t := expired; t != nil; {
+		next := t.next
+		t.bucket, t.prev, t.next = nil, nil, nil
+		fns = append(fns, t.fn)
+		if t.interval > 0 {
+			t.expire += t.interval
+			tw.place(t)
+		} else {
+			tw.size--
+		}
+		t = next
+	}
+	tw.mu.Unlock()
+
+	for _, fn := range fns {
+		fn()
+	}
+}
+
+// cascade 第一层转完一圈时, 将上层对应槽中的定时器重新分配到下层
+func (tw *TimeWheel) cascade() {
+	if tw.now&nearMask != 0 {
+		return
+	}
+	for i := range levels {
+		shift := nearBits + i*levelBits
+		idx := (tw.now >> shift) & levelMask
+		for t := tw.levels[i][idx].take(); t != nil; {
+			next := t.next
+			t.bucket, t.prev, t.next = nil, nil, nil
+			tw.place(t)
+			t = next
+		}
+		if idx != 0 {
+			return
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package timewheel
+
+import (
+	"context"
+	"math/rand/v2"
+	"testing"
+	"time"
+)
+
+// TestAfterFuncFiresOnTick 验证不同层级的定时器均在准确的 tick 触发
+func TestAfterFuncFiresOnTick(t *testing.T) {
+	tw := New(time.Millisecond)
+
+	const n = 20000
+	want := make([]uint64, n)
+	got := make([]uint64, n)
+	for i := range n {
+		d := uint64(rand.IntN(300000)) + 1
+		if i < 64 {
+			d = uint64(i) + 1
+		}
+		want[i] = d
+		tw.AfterFunc(time.Duration(d)*time.Millisecond, func() {
+			got[i] = tw.now
+		})
+	}
+	for range 300001 {
+		tw.Advance()
+	}
+	for i := range n {
+		if got[i] != want[i] {
+			t.Fatalf("timer %d: want tick %d, got %d", i, want[i], got[i])
+		}
+	}
+	if tw.Len() != 0 {
+		t.Fatalf("unexpected pending timers: %d", tw.Len())
+	}
+}
+
+// TestEveryAndStop 验证周期定时器与取消
+func TestEveryAndStop(t *testing.T) {
+	tw := New(time.Millisecond)
+
+	var fired []uint64
+	every := tw.Every(300*time.Millisecond, func() {
+		fired = append(fired, tw.now)
+	})
+	stopped := tw.AfterFunc(10*time.Millisecond, func() {
+		t.Fatalf("stopped timer fired")
+	})
+	if !stopped.Stop() || stopped.Stop() {
+		t.Fatalf("unexpected stop result")
+	}
+	for range 1000 {
+		tw.Advance()
+	}
+	if len(fired) != 3 || fired[0] != 300 || fired[1] != 600 || fired[2] != 900 {
+		t.Fatalf("unexpected fires: %v", fired)
+	}
+	if !every.Stop() {
+		t.Fatalf("periodic timer should be pending")
+	}
+	for range 1000 {
+		tw.Advance()
+	}
+	if len(fired) != 3 || tw.Len() != 0 {
+		t.Fatalf("periodic timer not stopped: %v", fired)
+	}
+}
+
+// TestRun 验证驱动协程按真实时间推进
+func TestRun(t *testing.T) {
+	tw := New(time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go tw.Run(ctx)
+
+	done := make(chan struct{})
+	tw.AfterFunc(20*time.Millisecond, func() {
+		close(done)
+	})
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("timer not fired")
+	}
+}
+
+func BenchmarkAfterFuncStop(b *testing.B) {
+	tw := New(time.Millisecond)
+	for i := range b.N {
+		tw.AfterFunc(time.Duration(i%100000)*time.Millisecond, func() {}).Stop()
+	}
+}
+
+func BenchmarkAdvance100k(b *testing.B) {
+	tw := New(time.Millisecond)
+	for i := range 100000 {
+		tw.Every(time.Duration(i%60000+1)*time.Millisecond, func() {})
+	}
+	b.ResetTimer()
+	for range b.N {
+		tw.Advance()
+	}
+}
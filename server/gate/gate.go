@@ -8,18 +8,16 @@ import (
 	"net/http"
 	"net/url"
 	"sync"
+	"sync/atomic"
 
 	"github.com/olahol/melody"
-	"golang.org/x/sync/singleflight"
 
 	"github.com/byteweap/meta"
 	"github.com/byteweap/meta/component/broker"
 	"github.com/byteweap/meta/component/log"
-	"github.com/byteweap/meta/component/registry"
 	"github.com/byteweap/meta/component/selector"
 	es "github.com/byteweap/meta/errors"
 	"github.com/byteweap/meta/internal/cluster"
-	"github.com/byteweap/meta/pkg/async"
 	"github.com/byteweap/meta/pkg/endpoint"
 	"github.com/byteweap/meta/pkg/host"
 	"github.com/byteweap/meta/server"
@@ -37,11 +35,14 @@ type Gate struct {
 	endpoint *url.URL       // server endpoint
 	ws       *melody.Melody // WebSocket server
 	sessions *Sessions      // player sessions
+	paused   sync.Map       // 迁移中暂停转发的消息缓冲 key: pauseKey, value: *pauseBuffer
+	seqs     sync.Map       // 玩家 seq 状态, 断线后保留一段时间以便重连沿用 key: uid, value: *seqState
 
-	mu        sync.RWMutex
-	selectors map[string]selector.Selector // 服务节点选择器 key: 服务名
-	watchers  map[string]registry.Watcher  // 服务节点监听器 key: 服务名
-	sfg       singleflight.Group
+	selectors *cluster.Selectors // 服务节点选择器
+
+	admin *http.Server // 管理接口服务
+
+	panics atomic.Uint64 // 消息处理异常次数
 }
 
 var _ server.Server = (*Gate)(nil)
@@ -58,8 +59,7 @@ func New(opts ...Option) *Gate {
 		opts:      o,
 		Server:    &http.Server{},
 		sessions:  newSessions(),
-		selectors: make(map[string]selector.Selector),
-		watchers:  make(map[string]registry.Watcher),
+		selectors: cluster.NewSelectors(o.discovery, o.selectorFunc),
 	}
 }
 
@@ -111,6 +111,7 @@ func (g *Gate) setup(name, appID string, ctx context.Context) error {
 	m.HandleDisconnect(g.handleDisconnect)
 	m.HandleMessage(g.handleTextMessage)
 	m.HandleMessageBinary(g.handleBinaryMessage)
+	m.HandleSentMessageBinary(g.handleSentBinary)
 	m.HandleError(g.handleError)
 	m.HandleClose(g.handleClose)
 
@@ -146,6 +147,11 @@ func (g *Gate) Start(ctx context.Context) error {
 		return fmt.Errorf("loop failed: %w", err)
 	}
 
+	// 管理接口
+	if err := g.serveAdmin(); err != nil {
+		return fmt.Errorf("admin serve failed: %w", err)
+	}
+
 	log.Infof("[gate] server started")
 	log.Infof("[websocket] server listening on: %s", g.ln.Addr().String())
 
@@ -171,14 +177,17 @@ func (g *Gate) Stop(ctx context.Context) error {
 
 	err := errors.Join(e1, e2)
 
-	// 3. 停止监听器
-	g.mu.Lock()
-	for _, watcher := range g.watchers {
-		if e := watcher.Stop(); e != nil {
+	// 3. 停止管理接口
+	if g.admin != nil {
+		if e := g.admin.Shutdown(ctx); e != nil {
 			err = errors.Join(err, e)
 		}
 	}
-	g.mu.Unlock()
+
+	// 4. 停止监听器
+	if e := g.selectors.Stop(); e != nil {
+		err = errors.Join(err, e)
+	}
 
 	if err != nil {
 		return err
@@ -238,10 +247,6 @@ func (g *Gate) loop() error {
 	// 处理收到的消息
 	go func(ctx context.Context, sub broker.Subscription, ch <-chan *broker.Message) {
 		defer func() {
-			// 异常捕获,防止崩溃
-			async.Recover(func(r any) {
-				log.Errorf("gate handler panic error: %v", r)
-			})
 			if err = sub.Close(); err != nil {
 				log.Errorf("gate close subscription error: %v", err)
 			}
@@ -259,6 +264,9 @@ func (g *Gate) loop() error {
 		}
 	}(g.ctx, sub, msgChan)
 
+	// 空闲会话检测
+	go g.evictIdle()
+
 	return nil
 }
 
@@ -267,73 +275,7 @@ func (g *Gate) Subject(fromApp string) string {
 	return cluster.Subject(g.opts.prefix, fromApp, g.appName, g.appID)
 }
 
-// 确保选择器
+// 确保选择器, 首次调用时创建并监听服务节点变化
 func (g *Gate) ensure(service string) (selector.Selector, error) {
-	g.mu.RLock()
-	sel := g.selectors[service]
-	g.mu.RUnlock()
-	if sel != nil {
-		return sel, nil
-	}
-
-	v, err, _ := g.sfg.Do(service, func() (any, error) {
-		g.mu.RLock()
-		if existing := g.selectors[service]; existing != nil {
-			g.mu.RUnlock()
-			return existing, nil
-		}
-		g.mu.RUnlock()
-
-		created := g.opts.selectorFunc()
-		w, err := g.opts.discovery.Watch(g.ctx, service)
-		if err != nil {
-			log.Errorf("[websocket] ensure watch service error, service: %v, err: %v", service, err)
-			return nil, err
-		}
-
-		g.mu.Lock()
-		if existing := g.selectors[service]; existing != nil {
-			g.mu.Unlock()
-			if stopErr := w.Stop(); stopErr != nil {
-				log.Errorf("[websocket] ensure stop redundant watcher error, service: %v, err: %v", service, stopErr)
-			}
-			return existing, nil
-		}
-		g.selectors[service] = created
-		g.watchers[service] = w
-		g.mu.Unlock()
-
-		go g.watchSelector(created, w)
-		return created, nil
-	})
-	if err != nil {
-		return nil, err
-	}
-	return v.(selector.Selector), nil
-}
-
-func (g *Gate) watchSelector(sel selector.Selector, w registry.Watcher) {
-	for {
-		select {
-		case <-g.ctx.Done():
-			return
-		default:
-			instances, err := w.Next()
-			if err != nil {
-				return
-			}
-			nodes := make([]selector.Node, 0, len(instances))
-			for _, instance := range instances {
-				nodes = append(nodes,
-					selector.NewNode(
-						instance.ID,
-						instance.Name,
-						instance.Version,
-						instance.Metadata,
-					),
-				)
-			}
-			sel.Update(nodes) // 更新节点
-		}
-	}
+	return g.selectors.Get(g.ctx, service)
 }
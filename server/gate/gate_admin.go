@@ -0,0 +1,223 @@
+package gate
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/olahol/melody"
+
+	"github.com/byteweap/meta/component/log"
+	"github.com/byteweap/meta/component/selector"
+	"github.com/byteweap/meta/internal/cluster"
+)
+
+const connectedAtKey = "connected_at" // 会话中存放连接建立时间的 key
+
+// WatcherStatus 服务节点监听状态
+type WatcherStatus struct {
+	Service   string    `json:"service"`
+	Running   bool      `json:"running"`
+	StartedAt time.Time `json:"started_at"`
+	UpdatedAt time.Time `json:"updated_at,omitzero"`
+	Updates   int       `json:"updates"`
+	Nodes     int       `json:"nodes"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// SessionInfo 会话详情
+type SessionInfo struct {
+	Uid         int64     `json:"uid"`
+	RemoteAddr  string    `json:"remote_addr"`
+	ConnectedAt time.Time `json:"connected_at,omitzero"`
+	Secure      bool      `json:"secure"`
+	LastSeq     uint64    `json:"last_seq"`
+}
+
+// NodeInfo 服务节点信息
+type NodeInfo struct {
+	ID      string            `json:"id"`
+	Service string            `json:"service"`
+	Version string            `json:"version"`
+	Weight  float64           `json:"weight"`
+	Meta    map[string]string `json:"meta,omitempty"`
+}
+
+// serveAdmin 启动管理接口, 未配置地址时不启动
+func (g *Gate) serveAdmin() error {
+	if g.opts.adminAddr == "" {
+		return nil
+	}
+	ln, err := net.Listen("tcp", adminListenAddr(g.opts.adminAddr))
+	if err != nil {
+		return err
+	}
+	g.admin = &http.Server{Handler: g.adminHandler()}
+	go func() {
+		if err := g.admin.Serve(ln); err != nil && err != http.ErrServerClosed {
+			log.Errorf("[admin] server serve error: %v", err)
+		}
+	}()
+	log.Infof("[admin] server listening on: %s", ln.Addr().String())
+	return nil
+}
+
+// adminListenAddr 未指定主机的地址仅监听 127.0.0.1
+func adminListenAddr(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || host != "" {
+		return addr
+	}
+	return net.JoinHostPort("127.0.0.1", port)
+}
+
+// adminHandler 管理接口路由, 依次经过 AdminToken 校验与 AdminMiddleware
+//
+//	GET  /sessions            会话数量与 uid 列表
+//	GET  /sessions/count      会话数量
+//	GET  /sessions/{uid}      会话详情
+//	POST /sessions/{uid}/kick 踢下线
+//	GET  /services            各服务的选择器节点
+//	GET  /watchers            各服务的节点监听状态
+func (g *Gate) adminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /sessions", g.adminSessions)
+	mux.HandleFunc("GET /sessions/count", g.adminSessionCount)
+	mux.HandleFunc("GET /sessions/{uid}", g.adminSession)
+	mux.HandleFunc("POST /sessions/{uid}/kick", g.adminKick)
+	mux.HandleFunc("GET /services", g.adminServices)
+	mux.HandleFunc("GET /watchers", g.adminWatchers)
+
+	var h http.Handler = mux
+	if mw := g.opts.adminMiddleware; mw != nil {
+		h = mw(h)
+	}
+	if token := g.opts.adminToken; token != "" {
+		h = adminAuth(token, h)
+	}
+	return h
+}
+
+// adminAuth 校验 Bearer 令牌
+func adminAuth(token string, next http.Handler) http.Handler {
+	want := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="gate admin"`)
+			writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "unauthorized"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (g *Gate) adminSessions(w http.ResponseWriter, _ *http.Request) {
+	uids := make([]int64, 0)
+	g.sessions.rangeAll(func(uid int64, _ *melody.Session) bool {
+		uids = append(uids, uid)
+		return true
+	})
+	slices.Sort(uids)
+	writeJSON(w, http.StatusOK, map[string]any{"count": len(uids), "uids": uids})
+}
+
+func (g *Gate) adminSessionCount(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"count": g.sessions.count()})
+}
+
+func (g *Gate) adminSession(w http.ResponseWriter, r *http.Request) {
+	uid, s, ok := g.adminLookup(w, r)
+	if !ok {
+		return
+	}
+	info := SessionInfo{
+		Uid:        uid,
+		RemoteAddr: sessionRemoteAddr(s),
+		Secure:     secureOf(s) != nil,
+	}
+	if v, exists := s.Get(connectedAtKey); exists {
+		info.ConnectedAt = v.(time.Time)
+	}
+	if st := seqOf(s); st != nil {
+		st.mu.Lock()
+		info.LastSeq = st.last
+		st.mu.Unlock()
+	}
+	writeJSON(w, http.StatusOK, info)
+}
+
+func (g *Gate) adminKick(w http.ResponseWriter, r *http.Request) {
+	uid, s, ok := g.adminLookup(w, r)
+	if !ok {
+		return
+	}
+	// 先广播踢下线事件, 断开连接后由 handleDisconnect 完成注销、解绑与掉线事件广播
+	g.broadcastEvent(uid, cluster.Event_Kicked, metadataOf(s))
+	if err := s.CloseWithMsg(websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "kicked")); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	log.Infof("[admin] kick session, uid: %v", uid)
+	writeJSON(w, http.StatusOK, map[string]any{"uid": uid, "kicked": true})
+}
+
+func (g *Gate) adminServices(w http.ResponseWriter, _ *http.Request) {
+	services := make(map[string][]NodeInfo)
+	g.selectors.Range(func(service string, sel selector.Selector) bool {
+		nodes := sel.Nodes()
+		infos := make([]NodeInfo, 0, len(nodes))
+		for _, n := range nodes {
+			infos = append(infos, NodeInfo{
+				ID:      n.ID(),
+				Service: n.Service(),
+				Version: n.Version(),
+				Weight:  n.Weight(),
+				Meta:    n.Meta(),
+			})
+		}
+		services[service] = infos
+		return true
+	})
+	writeJSON(w, http.StatusOK, services)
+}
+
+func (g *Gate) adminWatchers(w http.ResponseWriter, _ *http.Request) {
+	statuses := g.selectors.Statuses()
+	watchers := make([]WatcherStatus, 0, len(statuses))
+	for _, st := range statuses {
+		watchers = append(watchers, WatcherStatus(st))
+	}
+	slices.SortFunc(watchers, func(a, b WatcherStatus) int {
+		return strings.Compare(a.Service, b.Service)
+	})
+	writeJSON(w, http.StatusOK, watchers)
+}
+
+// adminLookup 解析路径中的 uid 并查找会话, 失败时直接写入错误响应
+func (g *Gate) adminLookup(w http.ResponseWriter, r *http.Request) (int64, *melody.Session, bool) {
+	uid, err := strconv.ParseInt(r.PathValue("uid"), 10, 64)
+	if err != nil || uid <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid uid"})
+		return 0, nil, false
+	}
+	s, ok := g.sessions.get(uid)
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]any{"error": "session not found"})
+		return 0, nil, false
+	}
+	return uid, s, true
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Errorf("[admin] write response error: %v", err)
+	}
+}
@@ -2,6 +2,7 @@ package gate
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/olahol/melody"
@@ -23,6 +24,14 @@ func (g *Gate) handleConnect(s *melody.Session) {
 		_ = s.Close()
 		return
 	}
+	// 密钥交换
+	if g.opts.identity != nil {
+		if err := g.handshake(s); err != nil {
+			log.Errorf("[websocket] secure handshake error, uid: %v, err: %v", uid, err)
+			_ = s.CloseWithMsg(websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "secure handshake error"))
+			return
+		}
+	}
 	// 注册会话
 	session, ok := g.sessions.get(uid)
 	if ok {
@@ -30,6 +39,15 @@ func (g *Gate) handleConnect(s *melody.Session) {
 		_ = session.Close()
 	}
 	s.Set("uid", uid)
+	s.Set(connectedAtKey, time.Now())
+	if g.opts.mdExtractor != nil {
+		s.Set(metadataKey, g.opts.mdExtractor(req, uid))
+	}
+	touch(s)
+	if g.seqEnabled() {
+		s.Set(seqKey, g.attachSeq(uid))
+	}
+	g.openOutbox(s)
 	g.sessions.register(uid, s)
 
 	log.Infof("[websocket] new connection success, uid: %v, %s", uid, sessionRemoteAddr(s))
@@ -40,6 +58,7 @@ func (g *Gate) handleConnect(s *melody.Session) {
 	if err := loc.Bind(g.ctx, uid, g.appName, g.appID); err != nil {
 		log.Errorf("[websocket] new connection success, bind gate error, uid: %v, err: %v", uid, err)
 		g.sessions.unregister(uid)
+		outboxOf(s).close()
 		_ = s.Write([]byte(http.StatusText(http.StatusInternalServerError)))
 		_ = s.CloseWithMsg(websocket.FormatCloseMessage(melody.CloseInternalServerErr, "bind gate error"))
 		return
@@ -50,7 +69,7 @@ func (g *Gate) handleConnect(s *melody.Session) {
 	if ok {
 		event = cluster.Event_Reconnect
 	}
-	g.broadcastEvent(uid, event)
+	g.broadcastEvent(uid, event, metadataOf(s))
 }
 
 // 连接断开时调用
@@ -63,6 +82,11 @@ func (g *Gate) handleDisconnect(s *melody.Session) {
 	}
 	uid := uids.(int64)
 
+	// 关闭下行队列
+	if ob := outboxOf(s); ob != nil {
+		ob.close()
+	}
+
 	// 注销会话
 	curSession, yes := g.sessions.get(uid)
 	if !yes {
@@ -74,6 +98,7 @@ func (g *Gate) handleDisconnect(s *melody.Session) {
 		return
 	}
 	g.sessions.unregister(uid)
+	g.detachSeq(uid, seqOf(s))
 
 	log.Infof("[websocket] connection disconnect success, uid: %v", uid)
 
@@ -83,7 +108,7 @@ func (g *Gate) handleDisconnect(s *melody.Session) {
 	}
 
 	// 广播掉线事件到上游服务
-	g.broadcastEvent(uid, cluster.Event_Offline)
+	g.broadcastEvent(uid, cluster.Event_Offline, metadataOf(s))
 }
 
 // 接收到文本消息时调用
@@ -93,12 +118,8 @@ func (g *Gate) handleTextMessage(s *melody.Session, msg []byte) {
 
 // 接收到二进制消息时调用
 func (g *Gate) handleBinaryMessage(s *melody.Session, msg []byte) {
+	defer g.recoverSession(s)
 
-	meta := &envelope.IMessage{}
-	if err := proto.Unmarshal(msg, meta); err != nil {
-		log.Errorf("[websocket] unmarshal envelope error: %v", err)
-		return
-	}
 	uids, ok := s.Get("uid")
 	if !ok {
 		log.Error("[websocket] handleBinaryMessage get uid error, session not contains uid key")
@@ -106,8 +127,34 @@ func (g *Gate) handleBinaryMessage(s *melody.Session, msg []byte) {
 	}
 	uid := uids.(int64)
 
+	data, seq, err := g.open(s, msg)
+	if err != nil {
+		log.Errorf("[websocket] decrypt message error, uid: %v, err: %v", uid, err)
+		return
+	}
+	meta := &envelope.IMessage{}
+	if err = proto.Unmarshal(data, meta); err != nil {
+		log.Errorf("[websocket] unmarshal envelope error: %v", err)
+		return
+	}
+	// 加密帧的 seq 须与消息头一致, 防止篡改 seq 绕过重放校验
+	if g.opts.identity != nil && meta.GetHeader().GetSeq() != seq {
+		log.Errorf("[websocket] secure seq mismatch, uid: %v, frame seq: %v, header seq: %v", uid, seq, meta.GetHeader().GetSeq())
+		return
+	}
+	// 心跳由网关直接回复, 不转发至 mesh, 也不计入业务活跃
+	if g.isHeartbeat(meta) {
+		g.heartbeat(s, meta)
+		return
+	}
+	// seq 校验与重复请求抑制
+	if !g.checkSeq(s, uid, meta) {
+		return
+	}
+	touch(s)
+
 	// 业务消息分发
-	g.dispatch(uid, meta)
+	g.dispatch(uid, meta, metadataOf(s))
 }
 
 // 错误时调用
@@ -0,0 +1,96 @@
+package gate
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/olahol/melody"
+
+	"github.com/byteweap/meta/component/log"
+	"github.com/byteweap/meta/encoding/proto"
+	"github.com/byteweap/meta/envelope"
+)
+
+const activeKey = "active" // 会话中存放最近业务消息时间(UnixNano)的 key
+
+// isHeartbeat 是否为心跳消息
+// 开启 HeartbeatCmd 时, service 为空或为网关自身, 且 cmd 为配置的心跳指令的消息为心跳消息
+func (g *Gate) isHeartbeat(e *envelope.IMessage) bool {
+	if !g.opts.heartbeat || e.GetHeader().GetCmd() != g.opts.heartbeatCmd {
+		return false
+	}
+	service := e.GetService()
+	return service == "" || service == g.appName
+}
+
+// heartbeat 回复心跳, 响应头的 timestamp 为服务器当前时间(ms), 供客户端校时
+func (g *Gate) heartbeat(s *melody.Session, e *envelope.IMessage) {
+	header := e.GetHeader()
+	out := &envelope.OMessage{
+		Header: &envelope.Header{
+			Seq:       header.GetSeq(),
+			Cmd:       header.GetCmd(),
+			Version:   header.GetVersion(),
+			Timestamp: time.Now().UnixMilli(),
+		},
+		Service: g.appName,
+		MsgType: envelope.MsgType_RESPONSE,
+	}
+	data, err := proto.Marshal(out)
+	if err != nil {
+		log.Errorf("[websocket] marshal heartbeat error: %v", err)
+		return
+	}
+	if err = g.write(s, data); err != nil {
+		log.Errorf("[websocket] write heartbeat error: %v", err)
+	}
+}
+
+// touch 记录会话最近一次业务消息时间
+func touch(s *melody.Session) {
+	if v, ok := s.Get(activeKey); ok {
+		v.(*atomic.Int64).Store(time.Now().UnixNano())
+		return
+	}
+	active := &atomic.Int64{}
+	active.Store(time.Now().UnixNano())
+	s.Set(activeKey, active)
+}
+
+// idleSince 返回会话最近一次业务消息时间
+func idleSince(s *melody.Session) (time.Time, bool) {
+	v, ok := s.Get(activeKey)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(0, v.(*atomic.Int64).Load()), true
+}
+
+// evictIdle 定期断开长时间没有业务消息的会话
+// 断开后由 handleDisconnect 完成注销、解绑与掉线事件广播
+func (g *Gate) evictIdle() {
+	timeout := g.opts.idleTimeout
+	if timeout <= 0 {
+		return
+	}
+	ticker := time.NewTicker(max(timeout/2, 100*time.Millisecond))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-g.ctx.Done():
+			return
+		case now := <-ticker.C:
+			g.sessions.rangeAll(func(uid int64, s *melody.Session) bool {
+				since, ok := idleSince(s)
+				if !ok || now.Sub(since) < timeout {
+					return true
+				}
+				log.Infof("[websocket] evict idle session, uid: %v, idle: %v", uid, now.Sub(since))
+				_ = s.CloseWithMsg(websocket.FormatCloseMessage(websocket.CloseGoingAway, "idle timeout"))
+				return true
+			})
+		}
+	}
+}
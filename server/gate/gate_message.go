@@ -4,19 +4,30 @@ import (
 	"errors"
 	"net/http"
 
+	"github.com/olahol/melody"
+
 	"github.com/byteweap/meta/component/broker"
 	"github.com/byteweap/meta/component/log"
+	"github.com/byteweap/meta/component/selector"
 	"github.com/byteweap/meta/encoding/proto"
 	"github.com/byteweap/meta/envelope"
 	"github.com/byteweap/meta/internal/cluster"
+	"github.com/byteweap/meta/metadata"
 	"github.com/byteweap/meta/pkg/conv"
 )
 
+const metadataKey = "metadata" // 会话中存放元数据的 key
+
+var errRouteNotFound = errors.New("route not found")
+
 // handlerRequestReplyMessage 来自其它服务的(request-reply)消息
 func (g *Gate) handleRequestReplyMessage(msg *broker.Message) {
 	if msg == nil {
 		return
 	}
+	if g.handleMigrateMessage(msg) {
+		return
+	}
 	if err := g.replyError(msg, http.StatusNotImplemented, "gate request-reply is not implemented"); err != nil {
 		log.Errorf("[websocket] request-reply unsupported, reply error: %v", err)
 	}
@@ -35,7 +46,8 @@ func (g *Gate) handlePubSubMessage(msg *broker.Message) {
 		log.Errorf("[websocket] reply2player get session error, uid: %v", uid)
 		return
 	}
-	if err := session.WriteBinary(msg.Data); err != nil {
+	g.cacheResponse(session, msg.Data)
+	if err := g.write(session, msg.Data); err != nil {
 		log.Errorf("[websocket] reply2player write binary error, uid: %v, err: %v", uid, err)
 		return
 	}
@@ -43,7 +55,9 @@ func (g *Gate) handlePubSubMessage(msg *broker.Message) {
 }
 
 // 处理来自其它服务的消息
+// 每条消息独立捕获异常, 单条消息异常不影响后续消息处理
 func (g *Gate) handleMessage(msg *broker.Message) {
+	defer g.recoverMessage(msg)
 	if msg.Reply != "" {
 		g.handleRequestReplyMessage(msg)
 	} else {
@@ -67,48 +81,50 @@ func (g *Gate) replyError(reqMsg *broker.Message, code int, tip string) error {
 	return g.opts.broker.Reply(g.ctx, reqMsg, nil, broker.ReplyHeader(header))
 }
 
-// 业务消息分发至 mesh
-func (g *Gate) dispatch(uid int64, e *envelope.IMessage) {
+// 业务消息分发至 mesh, md 为连接的元数据, 随消息头透传
+func (g *Gate) dispatch(uid int64, e *envelope.IMessage, md metadata.Metadata) {
 
 	if e == nil {
 		log.Errorf("[websocket] dispatch error, envelope is nil")
 		return
 	}
+	// 玩家迁移中, 缓存至恢复
+	if g.buffer(uid, e, md) {
+		return
+	}
+	g.forward(uid, e, md)
+}
+
+// forward 选择节点并发布消息到 mesh, 不检查暂停状态
+func (g *Gate) forward(uid int64, e *envelope.IMessage, md metadata.Metadata) {
 	var (
 		toService = e.GetService()
-		loc, bro  = g.opts.locator, g.opts.broker
+		bro       = g.opts.broker
 	)
 
-	curNodeID, err := loc.Node(g.ctx, uid, toService)
-	if err != nil {
-		log.Errorf("[websocket] dispatch | get mesh node error, uid: %v, toService: %v, err: %v", uid, toService, err)
-		return
-	}
-
 	data, err := proto.Marshal(e)
 	if err != nil {
 		log.Errorf("[websocket] dispatch | marshal to mesh data error: %v", err)
 		return
 	}
-	nodeID := curNodeID
-	if curNodeID == "" {
-		sel, err := g.ensure(toService)
-		if err != nil {
-			log.Errorf("[websocket] dispatch | get mesh node error, uid: %v, toService: %v, err: %v", uid, toService, err)
-			return
-		}
-		node, err := sel.Select("")
-		if err != nil {
-			log.Errorf("[websocket] dispatch | select mesh node error, uid: %v, toService: %v, err: %v", uid, toService, err)
-			return
+	nodeID, err := g.route(uid, e)
+	if err != nil {
+		log.Errorf("[websocket] dispatch | route error, uid: %v, toService: %v, cmd: %v, version: %v, err: %v", uid, toService, e.GetHeader().GetCmd(), e.GetHeader().GetVersion(), err)
+		// 所有节点均未实现该路由, 直接回复客户端
+		if errors.Is(err, errRouteNotFound) {
+			if s, ok := g.sessions.get(uid); ok {
+				g.writeResult(s, e, http.StatusNotFound, "route not found")
+			}
 		}
-		nodeID = node.ID()
+		return
 	}
 	// 构建消息头
 	var (
 		reply  = g.Subject(toService) // 回复主题
 		header = cluster.BuildHeader(uid, cluster.Event_Business, reply, g.appName, toService)
 	)
+	cluster.SetFromNode(header, g.appID)
+	cluster.SetMetadata(header, md)
 	// 发布消息到 Mesh
 	subject := cluster.Subject(g.opts.prefix, g.appName, toService, nodeID)
 	if err = bro.Pub(g.ctx, subject, data, broker.PubHeader(header)); err != nil {
@@ -118,8 +134,51 @@ func (g *Gate) dispatch(uid int64, e *envelope.IMessage) {
 	log.Debugf("[websocket] dispatch success, uid: %v, subject: %v", uid, subject)
 }
 
-// 广播系统事件
-func (g *Gate) broadcastEvent(uid int64, event cluster.Event) {
+// route 选择目标节点: 无状态服务 > 玩家绑定节点 > 服务发现
+// 服务发现时只选择实现了 cmd/version 的节点(依据节点发布的路由摘要, 未发布摘要的节点视为全部实现)
+func (g *Gate) route(uid int64, e *envelope.IMessage) (string, error) {
+	toService := e.GetService()
+	if _, ok := g.opts.stateless[toService]; ok {
+		return cluster.AnyNode, nil
+	}
+	nodeID, err := g.opts.locator.Node(g.ctx, uid, toService)
+	if err != nil {
+		return "", err
+	}
+	if nodeID != "" {
+		return nodeID, nil
+	}
+	sel, err := g.ensure(toService)
+	if err != nil {
+		return "", err
+	}
+	filter := routeFilter(e.GetHeader().GetCmd(), e.GetHeader().GetVersion())
+	if nodes := sel.Nodes(); len(nodes) > 0 && len(filter(nodes)) == 0 {
+		return "", errRouteNotFound
+	}
+	node, err := sel.Select("", filter)
+	if err != nil {
+		return "", err
+	}
+	return node.ID(), nil
+}
+
+// routeFilter 筛选实现了 cmd/version 的节点, 含节点可通过版本回退或升级处理的版本
+func routeFilter(cmd, version uint32) selector.Filter {
+	return func(nodes []selector.Node) []selector.Node {
+		res := make([]selector.Node, 0, len(nodes))
+		for _, n := range nodes {
+			routes, ok := cluster.RoutesOf(n)
+			if !ok || routes.Has(cmd, version) {
+				res = append(res, n)
+			}
+		}
+		return res
+	}
+}
+
+// 广播系统事件, md 为连接的元数据, 随事件透传
+func (g *Gate) broadcastEvent(uid int64, event cluster.Event, md metadata.Metadata) {
 
 	// 获取玩家当前所在所有节点
 	snMap, err := g.opts.locator.AllNodes(g.ctx, uid)
@@ -136,6 +195,8 @@ func (g *Gate) broadcastEvent(uid int64, event cluster.Event) {
 			header  = cluster.BuildHeader(uid, event, g.Subject(service), g.appName, service)
 			subject = cluster.Subject(g.opts.prefix, g.appName, service, node)
 		)
+		cluster.SetFromNode(header, g.appID)
+		cluster.SetMetadata(header, md)
 		if err = g.opts.broker.Pub(g.ctx, subject, nil, broker.PubHeader(header)); err != nil {
 			log.Errorf("[websocket] broadcast event error, uid: %v, subject: %v, err: %v", uid, subject, err)
 			return
@@ -143,3 +204,13 @@ func (g *Gate) broadcastEvent(uid int64, event cluster.Event) {
 		log.Debugf("[websocket] broadcast event success, uid: %v, subject: %v, event: %v", uid, subject, event)
 	}
 }
+
+// metadataOf 获取会话元数据
+func metadataOf(s *melody.Session) metadata.Metadata {
+	v, ok := s.Get(metadataKey)
+	if !ok {
+		return nil
+	}
+	md, _ := v.(metadata.Metadata)
+	return md
+}
@@ -0,0 +1,148 @@
+package gate
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/byteweap/meta/component/broker"
+	"github.com/byteweap/meta/component/log"
+	"github.com/byteweap/meta/envelope"
+	"github.com/byteweap/meta/internal/cluster"
+	"github.com/byteweap/meta/metadata"
+)
+
+// defaultPauseTimeout 暂停请求未携带截止时间时暂停转发的最长时间
+// 超时自动恢复, 防止迁移方异常退出导致玩家消息永久挂起
+const defaultPauseTimeout = 10 * time.Second
+
+// pauseKey 暂停转发的玩家与目标服务
+type pauseKey struct {
+	uid     int64
+	service string
+}
+
+// pausedMessage 暂停期间缓存的上行消息
+type pausedMessage struct {
+	e  *envelope.IMessage
+	md metadata.Metadata
+}
+
+// pauseBuffer 暂停期间的消息缓冲
+type pauseBuffer struct {
+	mu     sync.Mutex
+	msgs   []pausedMessage
+	closed bool
+	timer  *time.Timer
+}
+
+// handleMigrateMessage 处理 mesh 迁移玩家时发起的暂停/恢复请求
+// 返回 false 表示非迁移请求
+func (g *Gate) handleMigrateMessage(msg *broker.Message) bool {
+	var (
+		uid     = cluster.GetUidBy(msg.Header)
+		service = cluster.GetFromServiceBy(msg.Header)
+	)
+	switch cluster.GetEventBy(msg.Header) {
+	case cluster.Event_Pause:
+		// 暂停至迁移截止时间(mesh.MigrateTimeout), 之后迁移方不再发送恢复请求
+		timeout := defaultPauseTimeout
+		if deadline, ok := cluster.GetDeadlineBy(msg.Header); ok {
+			timeout = time.Until(deadline)
+		}
+		g.pause(uid, service, timeout)
+	case cluster.Event_Resume:
+		g.resume(uid, service)
+	default:
+		return false
+	}
+	if err := g.replyError(msg, http.StatusOK, ""); err != nil {
+		log.Errorf("[websocket] migrate reply error, uid: %v, service: %v, err: %v", uid, service, err)
+	}
+	return true
+}
+
+// pause 暂停转发玩家发往 service 的消息, 期间的消息缓存至恢复时投递, 超过 timeout 自动恢复
+func (g *Gate) pause(uid int64, service string, timeout time.Duration) {
+	if timeout <= 0 {
+		log.Warnf("[websocket] pause deadline exceeded, ignored, uid: %v, service: %v", uid, service)
+		return
+	}
+	key := pauseKey{uid: uid, service: service}
+	buf := &pauseBuffer{}
+	for {
+		v, loaded := g.paused.LoadOrStore(key, buf)
+		if !loaded {
+			break
+		}
+		// 已暂停则忽略; 正在恢复时等待缓存消息投递完毕(缓冲随之移除)后重新暂停
+		existing := v.(*pauseBuffer)
+		existing.mu.Lock()
+		closed := existing.closed
+		existing.mu.Unlock()
+		if !closed {
+			return
+		}
+	}
+	buf.mu.Lock()
+	buf.timer = time.AfterFunc(timeout, func() {
+		log.Warnf("[websocket] pause timeout, resume automatically, uid: %v, service: %v", uid, service)
+		g.resume(uid, service)
+	})
+	buf.mu.Unlock()
+	log.Infof("[websocket] pause dispatch, uid: %v, service: %v", uid, service)
+}
+
+// resume 恢复转发, 按顺序投递暂停期间缓存的消息
+// 投递期间持有缓冲锁且缓冲仍保留在 paused 中, 并发到达的新消息在 buffer 中等待投递完毕后再转发, 保证顺序
+func (g *Gate) resume(uid int64, service string) {
+	key := pauseKey{uid: uid, service: service}
+	v, ok := g.paused.Load(key)
+	if !ok {
+		return
+	}
+	buf := v.(*pauseBuffer)
+	buf.mu.Lock()
+	defer buf.mu.Unlock()
+	if buf.closed { // 已由并发的恢复(如超时)投递
+		return
+	}
+	if buf.timer != nil {
+		buf.timer.Stop()
+	}
+	buf.closed = true
+	for _, m := range buf.msgs {
+		g.forward(uid, m.e, m.md)
+	}
+	g.paused.CompareAndDelete(key, buf)
+	log.Infof("[websocket] resume dispatch, uid: %v, service: %v, buffered: %v", uid, service, len(buf.msgs))
+	buf.msgs = nil
+}
+
+// buffer 转发已暂停时缓存消息, 返回 false 表示未暂停
+// 正在恢复时等待缓存消息投递完毕后返回 false; 缓冲已满时丢弃消息并回复客户端 503
+func (g *Gate) buffer(uid int64, e *envelope.IMessage, md metadata.Metadata) bool {
+	v, ok := g.paused.Load(pauseKey{uid: uid, service: e.GetService()})
+	if !ok {
+		return false
+	}
+	buf := v.(*pauseBuffer)
+	buf.mu.Lock() // 恢复中时阻塞至缓存消息投递完毕
+	if buf.closed {
+		buf.mu.Unlock()
+		return false
+	}
+	full := len(buf.msgs) >= g.opts.messageBufferSize
+	if !full {
+		buf.msgs = append(buf.msgs, pausedMessage{e: e, md: md})
+	}
+	buf.mu.Unlock()
+
+	if full {
+		log.Warnf("[websocket] pause buffer full, drop message, uid: %v, service: %v, cmd: %v", uid, e.GetService(), e.GetHeader().GetCmd())
+		if s, ok := g.sessions.get(uid); ok {
+			g.writeResult(s, e, http.StatusServiceUnavailable, "server busy")
+		}
+	}
+	return true
+}
@@ -0,0 +1,251 @@
+package gate
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/olahol/melody"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/byteweap/meta/component/log"
+	"github.com/byteweap/meta/envelope"
+)
+
+// SlowConsumerPolicy 会话下行队列超出上限时的处理策略
+type SlowConsumerPolicy int
+
+const (
+	DropPush   SlowConsumerPolicy = iota // 优先丢弃推送, 保留响应 [DEFAULT]
+	DropOldest                           // 丢弃最早入队的消息
+	Disconnect                           // 断开连接
+)
+
+const (
+	outboxKey    = "outbox"       // 会话中存放下行队列的 key
+	batchHeader  = "X-Meta-Batch" // 声明支持批量帧的请求头
+	batchQuery   = "batch"        // 声明支持批量帧的 query 参数
+	maxInflight  = 4              // 已提交给 websocket 但尚未写出的最大帧数
+	fieldHeader  = 1              // OMessage.header 字段号
+	fieldMsgType = 3              // OMessage.msg_type 字段号
+	fieldSeq     = 1              // Header.seq 字段号
+)
+
+// outbox 会话下行队列
+// 网关主循环只负责入队, 由每个会话独立的写协程出队并写出,
+// websocket 写出缓慢时消息在队列中累积, 支持批量的客户端会被合并为一帧
+type outbox struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	queue    [][]byte
+	limit    int                // 队列上限
+	batch    int                // 每帧最多合并的消息数, 1 表示不合并
+	policy   SlowConsumerPolicy // 超出上限时的策略
+	inflight int                // 已提交但尚未写出的帧数
+	closed   bool
+}
+
+func newOutbox(limit, batch int, policy SlowConsumerPolicy) *outbox {
+	ob := &outbox{
+		limit:  limit,
+		batch:  batch,
+		policy: policy,
+	}
+	ob.cond = sync.NewCond(&ob.mu)
+	return ob
+}
+
+// push 入队, 返回 false 表示会话应被断开
+func (ob *outbox) push(data []byte) bool {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+	if ob.closed {
+		return true
+	}
+	if len(ob.queue) >= ob.limit {
+		switch ob.policy {
+		case Disconnect:
+			return false
+		case DropOldest:
+			ob.queue = ob.queue[1:]
+		default:
+			if outboundType(data) == envelope.MsgType_PUSH {
+				return true
+			}
+			ob.queue = dropFirstPush(ob.queue)
+		}
+	}
+	ob.queue = append(ob.queue, data)
+	ob.cond.Signal()
+	return true
+}
+
+// pop 阻塞直到有消息可写且写出窗口未满, 队列关闭时返回 false
+func (ob *outbox) pop() ([][]byte, bool) {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+	for !ob.closed && (len(ob.queue) == 0 || ob.inflight >= maxInflight) {
+		ob.cond.Wait()
+	}
+	if ob.closed {
+		return nil, false
+	}
+	n := min(len(ob.queue), ob.batch)
+	out := make([][]byte, n)
+	copy(out, ob.queue[:n])
+	clear(ob.queue[:n])
+	ob.queue = ob.queue[n:]
+	ob.inflight++
+	return out, true
+}
+
+// sent websocket 写出一帧
+func (ob *outbox) sent() {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+	if ob.inflight > 0 {
+		ob.inflight--
+	}
+	ob.cond.Signal()
+}
+
+// close 关闭队列并唤醒写协程
+func (ob *outbox) close() {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+	ob.closed = true
+	ob.queue = nil
+	ob.cond.Broadcast()
+}
+
+// len 当前队列长度
+func (ob *outbox) len() int {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+	return len(ob.queue)
+}
+
+// outboxOf 获取会话下行队列
+func outboxOf(s *melody.Session) *outbox {
+	v, ok := s.Get(outboxKey)
+	if !ok {
+		return nil
+	}
+	return v.(*outbox)
+}
+
+// batchSupported 客户端是否在握手时声明支持批量帧
+func batchSupported(s *melody.Session) bool {
+	raw := s.Request.Header.Get(batchHeader)
+	if raw == "" {
+		raw = s.Request.URL.Query().Get(batchQuery)
+	}
+	ok, _ := strconv.ParseBool(raw)
+	return ok
+}
+
+// openOutbox 为会话创建下行队列并启动写协程
+func (g *Gate) openOutbox(s *melody.Session) {
+	batch := 1
+	if batchSupported(s) {
+		batch = g.opts.maxBatchSize
+	}
+	ob := newOutbox(g.opts.outboundQueueSize, batch, g.opts.slowConsumerPolicy)
+	s.Set(outboxKey, ob)
+	go g.flush(s, ob)
+}
+
+// flush 写协程, 按序写出下行队列中的消息
+func (g *Gate) flush(s *melody.Session, ob *outbox) {
+	for {
+		msgs, ok := ob.pop()
+		if !ok {
+			return
+		}
+		frame := msgs[0]
+		if ob.batch > 1 {
+			frame = encodeBatch(msgs)
+		}
+		if err := g.writeFrame(s, frame); err != nil {
+			log.Errorf("[websocket] flush outbox error, err: %v", err)
+			ob.sent()
+		}
+	}
+}
+
+// write 向会话写入下行消息
+// 会话存在下行队列时入队由写协程异步写出, 否则直接写出
+func (g *Gate) write(s *melody.Session, data []byte) error {
+	if ob := outboxOf(s); ob != nil {
+		g.enqueue(s, ob, data)
+		return nil
+	}
+	return g.writeFrame(s, data)
+}
+
+// enqueue 将下行消息放入会话队列
+func (g *Gate) enqueue(s *melody.Session, ob *outbox, data []byte) {
+	if ob.push(data) {
+		return
+	}
+	uid, _ := s.Get("uid")
+	log.Warnf("[websocket] disconnect slow consumer, uid: %v, queued: %v", uid, ob.len())
+	ob.close()
+	_ = s.CloseWithMsg(websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "slow consumer"))
+}
+
+// handleSentBinary websocket 写出二进制帧后调用
+func (g *Gate) handleSentBinary(s *melody.Session, _ []byte) {
+	if ob := outboxOf(s); ob != nil {
+		ob.sent()
+	}
+}
+
+// encodeBatch 将多条已序列化的 OMessage 拼接为 OBatch
+// repeated 消息字段与 repeated bytes 的编码一致, 无需重新序列化
+func encodeBatch(msgs [][]byte) []byte {
+	size := 0
+	for _, m := range msgs {
+		size += protowire.SizeTag(1) + protowire.SizeBytes(len(m))
+	}
+	out := make([]byte, 0, size)
+	for _, m := range msgs {
+		out = protowire.AppendTag(out, 1, protowire.BytesType)
+		out = protowire.AppendBytes(out, m)
+	}
+	return out
+}
+
+// outboundType 读取已序列化 OMessage 的消息类型, 无需完整反序列化
+func outboundType(data []byte) envelope.MsgType {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return envelope.MsgType_UNKNOWN
+		}
+		data = data[n:]
+		if num == fieldMsgType && typ == protowire.VarintType {
+			v, m := protowire.ConsumeVarint(data)
+			if m < 0 {
+				return envelope.MsgType_UNKNOWN
+			}
+			return envelope.MsgType(v)
+		}
+		m := protowire.ConsumeFieldValue(num, typ, data)
+		if m < 0 {
+			return envelope.MsgType_UNKNOWN
+		}
+		data = data[m:]
+	}
+	return envelope.MsgType_UNKNOWN
+}
+
+// dropFirstPush 丢弃队列中最早的推送消息, 不存在推送时丢弃最早的消息
+func dropFirstPush(queue [][]byte) [][]byte {
+	for i, m := range queue {
+		if outboundType(m) == envelope.MsgType_PUSH {
+			return append(queue[:i], queue[i+1:]...)
+		}
+	}
+	return queue[1:]
+}
@@ -0,0 +1,47 @@
+package gate
+
+import (
+	"net/http"
+	"runtime/debug"
+
+	"github.com/olahol/melody"
+
+	"github.com/byteweap/meta/component/broker"
+	"github.com/byteweap/meta/component/log"
+	"github.com/byteweap/meta/internal/cluster"
+)
+
+// Panics 返回消息处理异常次数
+func (g *Gate) Panics() uint64 {
+	return g.panics.Load()
+}
+
+// recoverMessage 捕获上游服务消息处理异常, request-reply 消息回复 500
+// 须以 defer 方式调用
+func (g *Gate) recoverMessage(msg *broker.Message) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	g.panics.Add(1)
+	log.Errorf("[websocket] handle message panic, uid: %v, error: %v\n%s", cluster.GetUidBy(msg.Header), r, debug.Stack())
+
+	if msg.Reply == "" {
+		return
+	}
+	if err := g.replyError(msg, http.StatusInternalServerError, "internal server error"); err != nil {
+		log.Errorf("[websocket] reply panic error: %v", err)
+	}
+}
+
+// recoverSession 捕获客户端消息处理异常, 避免读协程崩溃
+// 须以 defer 方式调用
+func (g *Gate) recoverSession(s *melody.Session) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	g.panics.Add(1)
+	uid, _ := s.Get("uid")
+	log.Errorf("[websocket] handle client message panic, uid: %v, error: %v\n%s", uid, r, debug.Stack())
+}
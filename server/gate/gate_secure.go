@@ -0,0 +1,93 @@
+package gate
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/olahol/melody"
+
+	"github.com/byteweap/meta/pkg/secure"
+)
+
+const (
+	secureKey    = "secure"     // 会话中存放加密通道的 key
+	secureHeader = "X-Meta-Key" // 握手请求头
+	secureQuery  = "key"        // 握手 query 参数
+)
+
+var errSecureKeyRequired = errors.New("secure key is required")
+
+// secureSession 会话加密通道
+type secureSession struct {
+	ch  *secure.Channel
+	mu  sync.Mutex // 保证下行帧按 seq 顺序入队
+	seq uint64     // 下行帧序号
+}
+
+// handshake 完成密钥交换, 并以首个二进制帧将握手消息(网关临时公钥 + 身份签名)下发给客户端
+func (g *Gate) handshake(s *melody.Session) error {
+	raw := s.Request.Header.Get(secureHeader)
+	if raw == "" {
+		raw = s.Request.URL.Query().Get(secureQuery)
+	}
+	if raw == "" {
+		return errSecureKeyRequired
+	}
+	peer, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(raw, "="))
+	if err != nil {
+		return secure.ErrInvalidKey
+	}
+	ch, hello, err := secure.ServerHello(g.opts.identity, peer)
+	if err != nil {
+		return err
+	}
+	s.Set(secureKey, &secureSession{ch: ch})
+	return s.WriteBinary(hello)
+}
+
+// secureOf 获取会话加密通道, 未开启加密时返回 nil
+func secureOf(s *melody.Session) *secureSession {
+	v, ok := s.Get(secureKey)
+	if !ok {
+		return nil
+	}
+	return v.(*secureSession)
+}
+
+// open 解密上行帧
+// 未开启加密时原样返回, seq 为 0
+func (g *Gate) open(s *melody.Session, frame []byte) ([]byte, uint64, error) {
+	if g.opts.identity == nil {
+		return frame, 0, nil
+	}
+	ss := secureOf(s)
+	if ss == nil {
+		return nil, 0, errSecureKeyRequired
+	}
+	seq, data, err := ss.ch.Open(frame)
+	if err != nil {
+		return nil, 0, err
+	}
+	return data, seq, nil
+}
+
+// writeFrame 向会话写入一帧, 开启加密时自动加密
+func (g *Gate) writeFrame(s *melody.Session, data []byte) error {
+	if g.opts.identity == nil {
+		return s.WriteBinary(data)
+	}
+	ss := secureOf(s)
+	if ss == nil {
+		return errSecureKeyRequired
+	}
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	ss.seq++
+	frame, err := ss.ch.Seal(ss.seq, data)
+	if err != nil {
+		return err
+	}
+	return s.WriteBinary(frame)
+}
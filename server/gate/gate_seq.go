@@ -0,0 +1,269 @@
+package gate
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/olahol/melody"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/byteweap/meta/component/log"
+	"github.com/byteweap/meta/encoding/proto"
+	"github.com/byteweap/meta/envelope"
+)
+
+// SeqPolicy 上行消息 seq 校验策略
+type SeqPolicy int
+
+const (
+	SeqNone   SeqPolicy = iota // 不校验 [DEFAULT]
+	SeqDrop                    // 丢弃重复/乱序消息
+	SeqReject                  // 丢弃重复/乱序消息, 并向客户端返回错误响应
+)
+
+const (
+	seqKey = "seq" // 会话中存放 seq 状态的 key
+
+	// codeSeqInvalid 重复/乱序消息的错误码
+	codeSeqInvalid = http.StatusConflict
+)
+
+// seqState 玩家 seq 状态, 按 uid 保存, 断线后保留 SeqStateTTL 供重连沿用
+type seqState struct {
+	mu       sync.Mutex
+	detached int64             // 断线时间(UnixNano), 0 表示在线
+	last     uint64            // 最近一次受理的 seq
+	size     int               // 响应缓存容量, 0 表示不缓存
+	cache    map[uint64][]byte // 已下发的响应 key: seq
+	order    []uint64          // 缓存淘汰顺序(FIFO)
+}
+
+func newSeqState(size int) *seqState {
+	st := &seqState{size: size}
+	if size > 0 {
+		st.cache = make(map[uint64][]byte, size)
+		st.order = make([]uint64, 0, size)
+	}
+	return st
+}
+
+// accept 受理 seq, 返回是否为新消息以及该 seq 已缓存的响应
+func (st *seqState) accept(seq uint64) (bool, []byte) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if seq > st.last {
+		st.last = seq
+		return true, nil
+	}
+	return false, st.cache[seq]
+}
+
+// store 缓存 seq 对应的响应
+func (st *seqState) store(seq uint64, data []byte) {
+	if st.size <= 0 {
+		return
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if _, ok := st.cache[seq]; !ok {
+		if len(st.order) >= st.size {
+			delete(st.cache, st.order[0])
+			st.order = st.order[1:]
+		}
+		st.order = append(st.order, seq)
+	}
+	st.cache[seq] = data
+}
+
+// attachSeq 连接建立时获取玩家 seq 状态, 断线未超过 SeqStateTTL 时沿用原状态
+func (g *Gate) attachSeq(uid int64) *seqState {
+	v, _ := g.seqs.LoadOrStore(uid, newSeqState(g.opts.respCacheSize))
+	st := v.(*seqState)
+	st.mu.Lock()
+	st.detached = 0
+	st.mu.Unlock()
+	return st
+}
+
+// detachSeq 连接断开时标记 seq 状态离线, SeqStateTTL 后仍未重连则移除
+func (g *Gate) detachSeq(uid int64, st *seqState) {
+	if st == nil {
+		return
+	}
+	now := time.Now().UnixNano()
+	st.mu.Lock()
+	st.detached = now
+	st.mu.Unlock()
+	time.AfterFunc(g.opts.seqStateTTL, func() {
+		st.mu.Lock()
+		expired := st.detached == now
+		st.mu.Unlock()
+		if expired {
+			g.seqs.CompareAndDelete(uid, st)
+		}
+	})
+}
+
+// seqOf 获取会话 seq 状态, 未开启校验时返回 nil
+func seqOf(s *melody.Session) *seqState {
+	v, ok := s.Get(seqKey)
+	if !ok {
+		return nil
+	}
+	return v.(*seqState)
+}
+
+// seqEnabled 是否需要跟踪 seq
+func (g *Gate) seqEnabled() bool {
+	return g.opts.seqPolicy != SeqNone || g.opts.respCacheSize > 0
+}
+
+// checkSeq 校验上行消息 seq, 返回 false 表示该消息不应继续分发
+// seq 为 0 的消息视为不参与排序, 直接放行
+// 客户端超时重发已处理过的请求时, 若响应仍在缓存中则直接重发缓存的响应,
+// 否则按策略丢弃或拒绝; SeqNone 策略(仅开启响应缓存)下照常分发
+func (g *Gate) checkSeq(s *melody.Session, uid int64, e *envelope.IMessage) bool {
+	seq := e.GetHeader().GetSeq()
+	if seq == 0 {
+		return true
+	}
+	st := seqOf(s)
+	if st == nil {
+		return true
+	}
+	fresh, cached := st.accept(seq)
+	if fresh {
+		return true
+	}
+	if cached != nil {
+		if err := g.write(s, cached); err != nil {
+			log.Errorf("[websocket] resend cached response error, uid: %v, seq: %v, err: %v", uid, seq, err)
+		}
+		log.Debugf("[websocket] resend cached response, uid: %v, seq: %v", uid, seq)
+		return false
+	}
+	if g.opts.seqPolicy == SeqNone {
+		return true
+	}
+
+	// 响应尚未返回(处理中)或已被淘汰的重复请求同样不再分发, 避免重复执行
+	if g.opts.seqPolicy == SeqReject {
+		log.Warnf("[websocket] reject duplicate or out-of-order message, uid: %v, seq: %v", uid, seq)
+		g.writeResult(s, e, codeSeqInvalid, "duplicate or out-of-order seq")
+		return false
+	}
+	log.Warnf("[websocket] drop duplicate or out-of-order message, uid: %v, seq: %v", uid, seq)
+	return false
+}
+
+// cacheResponse 缓存下行响应, 用于客户端重发时直接返回
+func (g *Gate) cacheResponse(s *melody.Session, data []byte) {
+	if g.opts.respCacheSize <= 0 {
+		return
+	}
+	st := seqOf(s)
+	if st == nil {
+		return
+	}
+	if seq := responseSeq(data); seq != 0 {
+		st.store(seq, data)
+	}
+}
+
+// responseSeq 读取已序列化 OMessage 的响应 seq, 无需完整反序列化
+// 非响应消息、未携带 seq 或无法解析时返回 0
+func responseSeq(data []byte) uint64 {
+	var (
+		seq      uint64
+		response bool
+		ok       bool
+	)
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return 0
+		}
+		data = data[n:]
+		switch {
+		case num == fieldHeader && typ == protowire.BytesType:
+			header, m := protowire.ConsumeBytes(data)
+			if m < 0 {
+				return 0
+			}
+			if seq, ok = consumeSeq(header, seq); !ok {
+				return 0
+			}
+			data = data[m:]
+		case num == fieldMsgType && typ == protowire.VarintType:
+			v, m := protowire.ConsumeVarint(data)
+			if m < 0 {
+				return 0
+			}
+			response = envelope.MsgType(v) == envelope.MsgType_RESPONSE
+			data = data[m:]
+		default:
+			m := protowire.ConsumeFieldValue(num, typ, data)
+			if m < 0 {
+				return 0
+			}
+			data = data[m:]
+		}
+	}
+	if !response {
+		return 0
+	}
+	return seq
+}
+
+// consumeSeq 读取已序列化 Header 的 seq, 未携带 seq 时返回 seq 原值, 无法解析时返回 false
+func consumeSeq(data []byte, seq uint64) (uint64, bool) {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return 0, false
+		}
+		data = data[n:]
+		if num == fieldSeq && typ == protowire.VarintType {
+			v, m := protowire.ConsumeVarint(data)
+			if m < 0 {
+				return 0, false
+			}
+			seq, data = v, data[m:]
+			continue
+		}
+		m := protowire.ConsumeFieldValue(num, typ, data)
+		if m < 0 {
+			return 0, false
+		}
+		data = data[m:]
+	}
+	return seq, true
+}
+
+// writeResult 由网关直接向客户端返回错误响应
+func (g *Gate) writeResult(s *melody.Session, e *envelope.IMessage, code int, tip string) {
+	header := e.GetHeader()
+	out := &envelope.OMessage{
+		Header: &envelope.Header{
+			Seq:       header.GetSeq(),
+			Cmd:       header.GetCmd(),
+			Version:   header.GetVersion(),
+			Timestamp: time.Now().UnixMilli(),
+		},
+		Service: g.appName,
+		MsgType: envelope.MsgType_RESPONSE,
+		Result: &envelope.Code{
+			Code: int32(code),
+			Tip:  tip,
+		},
+	}
+	data, err := proto.Marshal(out)
+	if err != nil {
+		log.Errorf("[websocket] marshal result error: %v", err)
+		return
+	}
+	if err = g.write(s, data); err != nil {
+		log.Errorf("[websocket] write result error, err: %v", err)
+	}
+}
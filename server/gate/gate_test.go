@@ -2,7 +2,10 @@ package gate
 
 import (
 	"context"
+	"crypto/ed25519"
+	"encoding/base64"
 	"errors"
+	"net/http"
 	"net/http/httptest"
 	"strings"
 	"sync"
@@ -18,6 +21,12 @@ import (
 	"github.com/byteweap/meta/component/locator"
 	"github.com/byteweap/meta/component/registry"
 	"github.com/byteweap/meta/component/selector"
+	"github.com/byteweap/meta/encoding/proto"
+	"github.com/byteweap/meta/envelope"
+	"github.com/byteweap/meta/internal/cluster"
+	"github.com/byteweap/meta/metadata"
+	"github.com/byteweap/meta/pkg/secure"
+	"github.com/byteweap/meta/server/mesh"
 )
 
 type testAppInfo struct {
@@ -38,6 +47,9 @@ func (s *testSubscription) Close() error { return nil }
 
 type testBroker struct {
 	mu          sync.Mutex
+	pubCalls    int
+	pubSubject  string
+	pubHeader   broker.Header
 	replyCalls  int
 	replyData   []byte
 	replyHeader broker.Header
@@ -45,7 +57,16 @@ type testBroker struct {
 
 func (b *testBroker) ID() string { return "test-broker" }
 
-func (b *testBroker) Pub(context.Context, string, []byte, ...broker.PublishOption) error {
+func (b *testBroker) Pub(_ context.Context, subject string, _ []byte, opts ...broker.PublishOption) error {
+	pubOpts := &broker.PublishOptions{}
+	for _, opt := range opts {
+		opt(pubOpts)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pubCalls++
+	b.pubSubject = subject
+	b.pubHeader = pubOpts.Header
 	return nil
 }
 
@@ -74,6 +95,8 @@ func (b *testBroker) Close() error { return nil }
 
 type testLocator struct {
 	mu          sync.Mutex
+	node        string
+	nodes       map[string]string // AllNodes 返回值 key: 服务名
 	bindErr     error
 	bindCalls   int
 	unbindCalls int
@@ -84,11 +107,14 @@ var _ locator.Locator = (*testLocator)(nil)
 func (l *testLocator) ID() string { return "test-locator" }
 
 func (l *testLocator) AllNodes(context.Context, int64) (map[string]string, error) {
-	return map[string]string{}, nil
+	if l.nodes == nil {
+		return map[string]string{}, nil
+	}
+	return l.nodes, nil
 }
 
 func (l *testLocator) Node(context.Context, int64, string) (string, error) {
-	return "", nil
+	return l.node, nil
 }
 
 func (l *testLocator) Bind(context.Context, int64, string, string) error {
@@ -108,11 +134,24 @@ func (l *testLocator) UnBind(context.Context, int64, string, string) error {
 func (l *testLocator) Close() error { return nil }
 
 type testWatcher struct {
+	mu        sync.Mutex
 	stopCalls int
 	nextErr   error
+	instances []*registry.ServiceInstance // 首次 Next 返回的实例
+	wait      chan struct{}               // 非 nil 时 Next 阻塞至关闭
 }
 
 func (w *testWatcher) Next() ([]*registry.ServiceInstance, error) {
+	w.mu.Lock()
+	instances := w.instances
+	w.instances = nil
+	w.mu.Unlock()
+	if instances != nil {
+		return instances, nil
+	}
+	if w.wait != nil {
+		<-w.wait
+	}
 	if w.nextErr != nil {
 		return nil, w.nextErr
 	}
@@ -120,6 +159,8 @@ func (w *testWatcher) Next() ([]*registry.ServiceInstance, error) {
 }
 
 func (w *testWatcher) Stop() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	w.stopCalls++
 	return nil
 }
@@ -163,13 +204,17 @@ type testSelector struct {
 	nodes []selector.Node
 }
 
-func (s *testSelector) Select(string, ...selector.Filter) (selector.Node, error) {
+func (s *testSelector) Select(_ string, filters ...selector.Filter) (selector.Node, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if len(s.nodes) == 0 {
+	nodes := s.nodes
+	for _, f := range filters {
+		nodes = f(nodes)
+	}
+	if len(nodes) == 0 {
 		return nil, selector.ErrNoAvailableNode
 	}
-	return s.nodes[0], nil
+	return nodes[0], nil
 }
 
 func (s *testSelector) Update(nodes []selector.Node) {
@@ -198,7 +243,7 @@ func TestGateValidate(t *testing.T) {
 }
 
 func TestEnsureRetriesAfterWatchFailure(t *testing.T) {
-	watcher := &testWatcher{nextErr: errors.New("stop")}
+	watcher := &testWatcher{nextErr: errors.New("stop"), wait: make(chan struct{})}
 	discovery := &testRegistry{
 		watchErrs: []error{errors.New("watch failed"), nil},
 		watcher:   watcher,
@@ -212,20 +257,25 @@ func TestEnsureRetriesAfterWatchFailure(t *testing.T) {
 
 	_, err := g.ensure("match")
 	require.EqualError(t, err, "watch failed")
-	require.Nil(t, g.selectors["match"])
-	require.Nil(t, g.watchers["match"])
+	require.False(t, g.selectors.Watching("match"))
 
 	sel, err := g.ensure("match")
 	require.NoError(t, err)
 	require.NotNil(t, sel)
-	require.NotNil(t, g.selectors["match"])
-	require.NotNil(t, g.watchers["match"])
+	require.True(t, g.selectors.Watching("match"))
 	require.Equal(t, 2, discovery.watchCalls)
+
+	// 监听中断后移除选择器, 下次访问时重建
+	close(watcher.wait)
+	require.Eventually(t, func() bool { return !g.selectors.Watching("match") }, time.Second, 5*time.Millisecond)
+	_, err = g.ensure("match")
+	require.NoError(t, err)
+	require.Equal(t, 3, discovery.watchCalls)
 }
 
 func TestEnsureSingleflightDeduplicatesConcurrentWatch(t *testing.T) {
 	discovery := &testRegistry{
-		watcher:   &testWatcher{nextErr: errors.New("stop")},
+		watcher:   &testWatcher{nextErr: errors.New("stop"), wait: make(chan struct{})},
 		watchWait: make(chan struct{}),
 	}
 	g := New(
@@ -255,8 +305,7 @@ func TestEnsureSingleflightDeduplicatesConcurrentWatch(t *testing.T) {
 		require.NoError(t, err)
 	}
 	require.Equal(t, 1, discovery.watchCalls)
-	require.NotNil(t, g.selectors["match"])
-	require.NotNil(t, g.watchers["match"])
+	require.True(t, g.selectors.Watching("match"))
 }
 
 func TestHandleConnectRollsBackSessionWhenBindFails(t *testing.T) {
@@ -348,3 +397,804 @@ func TestHandleDisconnectIgnoresStaleSession(t *testing.T) {
 	defer loc.mu.Unlock()
 	require.Equal(t, 0, loc.unbindCalls)
 }
+
+func TestSecureHandshakeAndEncryptedPush(t *testing.T) {
+	idPub, idPriv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	g, url := startTestGate(t, Secure(idPriv))
+
+	priv, err := secure.GenerateKey()
+	require.NoError(t, err)
+	conn, _, err := websocket.DefaultDialer.Dial(url+"?uid=42&key="+base64.RawURLEncoding.EncodeToString(priv.PublicKey().Bytes()), nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// 首帧为握手消息, 以预置的网关身份公钥校验
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, hello, err := conn.ReadMessage()
+	require.NoError(t, err)
+	ch, err := secure.OpenServerHello(idPub, priv, hello)
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		_, ok := g.sessions.get(42)
+		return ok
+	}, time.Second, 20*time.Millisecond)
+
+	g.handlePubSubMessage(&broker.Message{
+		Header: cluster.BuildHeader(42, cluster.Event_Business, "", "game", "gate"),
+		Data:   []byte("hello"),
+	})
+
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, frame, err := conn.ReadMessage()
+	require.NoError(t, err)
+	seq, plain, err := ch.Open(frame)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), seq)
+	require.Equal(t, []byte("hello"), plain)
+}
+
+func TestSecureHandshakeRejectsSubstitutedKey(t *testing.T) {
+	idPub, idPriv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	_, url := startTestGate(t, Secure(idPriv))
+
+	priv, err := secure.GenerateKey()
+	require.NoError(t, err)
+	conn, _, err := websocket.DefaultDialer.Dial(url+"?uid=42&key="+base64.RawURLEncoding.EncodeToString(priv.PublicKey().Bytes()), nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, hello, err := conn.ReadMessage()
+	require.NoError(t, err)
+
+	// 中间人替换握手消息中的网关临时公钥
+	fake, err := secure.GenerateKey()
+	require.NoError(t, err)
+	forged := append(fake.PublicKey().Bytes(), hello[secure.KeySize:]...)
+	_, err = secure.OpenServerHello(idPub, priv, forged)
+	require.ErrorIs(t, err, secure.ErrInvalidHello)
+
+	// 中间人以自身身份私钥与客户端单独握手
+	_, mitm, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	_, forged, err = secure.ServerHello(mitm, priv.PublicKey().Bytes())
+	require.NoError(t, err)
+	_, err = secure.OpenServerHello(idPub, priv, forged)
+	require.ErrorIs(t, err, secure.ErrInvalidHello)
+
+	_, err = secure.OpenServerHello(idPub, priv, hello)
+	require.NoError(t, err)
+}
+
+func TestSecureHandshakeRequiresKey(t *testing.T) {
+	g, url := startTestGate(t, Secure(ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))))
+
+	conn, _, err := websocket.DefaultDialer.Dial(url+"?uid=42", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, _, err = conn.ReadMessage()
+	require.Error(t, err)
+
+	_, ok := g.sessions.get(42)
+	require.False(t, ok)
+}
+
+func TestCheckSeqDropsDuplicateAndOutOfOrder(t *testing.T) {
+	g := New(SeqValidation(SeqDrop))
+	s := &melody.Session{Keys: map[string]any{seqKey: newSeqState(0)}}
+
+	msg := func(seq uint64) *envelope.IMessage {
+		return &envelope.IMessage{Header: &envelope.Header{Seq: seq, Cmd: 1, Version: 1}}
+	}
+	require.True(t, g.checkSeq(s, 7, msg(1)))
+	require.True(t, g.checkSeq(s, 7, msg(3)))
+	require.False(t, g.checkSeq(s, 7, msg(3)))
+	require.False(t, g.checkSeq(s, 7, msg(2)))
+	require.True(t, g.checkSeq(s, 7, msg(0)))
+	require.True(t, g.checkSeq(s, 7, msg(4)))
+}
+
+func TestResponseCacheSuppressesRetry(t *testing.T) {
+	g := New(ResponseCache(2))
+	st := newSeqState(2)
+	s := &melody.Session{Keys: map[string]any{seqKey: st}}
+
+	resp := func(seq uint64, typ envelope.MsgType) []byte {
+		data, err := proto.Marshal(&envelope.OMessage{Header: &envelope.Header{Seq: seq}, MsgType: typ})
+		require.NoError(t, err)
+		return data
+	}
+	req := &envelope.IMessage{Header: &envelope.Header{Seq: 1}}
+	require.True(t, g.checkSeq(s, 7, req))
+
+	// 推送不缓存
+	g.cacheResponse(s, resp(1, envelope.MsgType_PUSH))
+	_, cached := st.accept(1)
+	require.Nil(t, cached)
+
+	g.cacheResponse(s, resp(1, envelope.MsgType_RESPONSE))
+	_, cached = st.accept(1)
+	require.NotNil(t, cached)
+	require.False(t, g.checkSeq(s, 7, req))
+
+	// 超出容量时淘汰最早的响应
+	g.cacheResponse(s, resp(2, envelope.MsgType_RESPONSE))
+	g.cacheResponse(s, resp(3, envelope.MsgType_RESPONSE))
+	_, cached = st.accept(1)
+	require.Nil(t, cached)
+	require.Len(t, st.cache, 2)
+	require.NotNil(t, st.cache[3])
+}
+
+func TestResponseSeq(t *testing.T) {
+	marshal := func(out *envelope.OMessage) []byte {
+		data, err := proto.Marshal(out)
+		require.NoError(t, err)
+		return data
+	}
+	resp := marshal(&envelope.OMessage{
+		Header:  &envelope.Header{Seq: 7, Cmd: 5, Version: 2, Timestamp: time.Now().UnixMilli()},
+		Service: "game",
+		MsgType: envelope.MsgType_RESPONSE,
+		Payload: []byte("payload"),
+		Result:  &envelope.Code{Code: 404, Tip: "not found"},
+	})
+	require.EqualValues(t, 7, responseSeq(resp))
+	require.Zero(t, responseSeq(marshal(&envelope.OMessage{Header: &envelope.Header{Seq: 7}, MsgType: envelope.MsgType_PUSH})))
+	require.Zero(t, responseSeq(marshal(&envelope.OMessage{MsgType: envelope.MsgType_RESPONSE})))
+	require.Zero(t, responseSeq(resp[:len(resp)-1]))
+	require.Zero(t, testing.AllocsPerRun(100, func() { responseSeq(resp) }))
+}
+
+func TestSeqNoneOnlyServesCacheHits(t *testing.T) {
+	g := New(ResponseCache(2))
+	st := g.attachSeq(7)
+	s := &melody.Session{Keys: map[string]any{seqKey: st}}
+
+	req := &envelope.IMessage{Header: &envelope.Header{Seq: 2}}
+	require.True(t, g.checkSeq(s, 7, req))
+	// 未命中缓存的重复与乱序请求照常分发
+	require.True(t, g.checkSeq(s, 7, req))
+	require.True(t, g.checkSeq(s, 7, &envelope.IMessage{Header: &envelope.Header{Seq: 1}}))
+}
+
+func TestSeqStateSurvivesReconnect(t *testing.T) {
+	g := New(ResponseCache(2), SeqStateTTL(20*time.Millisecond))
+	st := g.attachSeq(7)
+	st.accept(1)
+	st.store(1, []byte("resp"))
+
+	// TTL 内重连沿用原状态, 重发的请求命中缓存
+	g.detachSeq(7, st)
+	require.Same(t, st, g.attachSeq(7))
+	_, cached := st.accept(1)
+	require.Equal(t, []byte("resp"), cached)
+
+	// 超过 TTL 未重连则移除
+	g.detachSeq(7, st)
+	require.Eventually(t, func() bool {
+		_, ok := g.seqs.Load(int64(7))
+		return !ok
+	}, time.Second, 5*time.Millisecond)
+	require.NotSame(t, st, g.attachSeq(7))
+}
+
+func TestAdminHandler(t *testing.T) {
+	watcher := &testWatcher{
+		nextErr:   errors.New("stop"),
+		wait:      make(chan struct{}),
+		instances: []*registry.ServiceInstance{{ID: "game-1", Name: "game", Version: "v1", Metadata: map[string]string{"weight": "10"}}},
+	}
+	defer close(watcher.wait)
+	g := New(
+		Discovery(&testRegistry{watcher: watcher}),
+		SelectorFunc(func() selector.Selector { return &testSelector{} }),
+	)
+	now := time.Now()
+	g.sessions.register(9, &melody.Session{Keys: map[string]any{"uid": int64(9), connectedAtKey: now}})
+	g.sessions.register(3, &melody.Session{Keys: map[string]any{"uid": int64(3), seqKey: &seqState{last: 5}}})
+
+	_, err := g.ensure("game")
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		st := g.selectors.Statuses()
+		return len(st) == 1 && st[0].Nodes == 1
+	}, time.Second, 5*time.Millisecond)
+
+	h := g.adminHandler()
+	do := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+
+	rec := do("GET", "/sessions")
+	require.Equal(t, 200, rec.Code)
+	require.JSONEq(t, `{"count":2,"uids":[3,9]}`, rec.Body.String())
+
+	rec = do("GET", "/sessions/count")
+	require.JSONEq(t, `{"count":2}`, rec.Body.String())
+
+	rec = do("GET", "/sessions/3")
+	require.Equal(t, 200, rec.Code)
+	require.Contains(t, rec.Body.String(), `"last_seq":5`)
+
+	require.Equal(t, 404, do("GET", "/sessions/100").Code)
+	require.Equal(t, 400, do("GET", "/sessions/abc").Code)
+	require.Equal(t, 404, do("POST", "/sessions/100/kick").Code)
+
+	rec = do("GET", "/services")
+	require.Equal(t, 200, rec.Code)
+	require.JSONEq(t, `{"game":[{"id":"game-1","service":"game","version":"v1","weight":10,"meta":{"weight":"10"}}]}`, rec.Body.String())
+
+	rec = do("GET", "/watchers")
+	require.Equal(t, 200, rec.Code)
+	require.Contains(t, rec.Body.String(), `"service":"game","running":true`)
+	require.Contains(t, rec.Body.String(), `"nodes":1`)
+}
+
+func TestAdminAuth(t *testing.T) {
+	audited := 0
+	g := New(
+		AdminToken("secret"),
+		AdminMiddleware(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				audited++
+				next.ServeHTTP(w, r)
+			})
+		}),
+	)
+	h := g.adminHandler()
+	do := func(auth string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/sessions/42/kick", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	require.Equal(t, http.StatusUnauthorized, do("").Code)
+	require.Equal(t, http.StatusUnauthorized, do("Bearer wrong").Code)
+	require.Equal(t, 0, audited)
+	require.Equal(t, http.StatusNotFound, do("Bearer secret").Code)
+	require.Equal(t, 1, audited)
+}
+
+func TestAdminListenAddr(t *testing.T) {
+	require.Equal(t, "127.0.0.1:9100", adminListenAddr(":9100"))
+	require.Equal(t, "0.0.0.0:9100", adminListenAddr("0.0.0.0:9100"))
+	require.Equal(t, "10.0.0.1:9100", adminListenAddr("10.0.0.1:9100"))
+}
+
+// startTestGate 启动一个使用测试组件的网关, 返回 websocket 地址前缀
+func startTestGate(t *testing.T, opts ...Option) (*Gate, string) {
+	t.Helper()
+
+	opts = append([]Option{
+		Addr("127.0.0.1:0"),
+		Path("/ws"),
+		Locator(&testLocator{}),
+		Broker(&testBroker{}),
+		Discovery(&testRegistry{}),
+		SelectorFunc(func() selector.Selector { return &testSelector{} }),
+	}, opts...)
+	g := New(opts...)
+
+	ctx, cancel := context.WithCancel(meta.NewContext(context.Background(), testAppInfo{id: "gate-1", name: "gate"}))
+	require.NoError(t, g.setup("gate", "gate-1", ctx))
+	ts := httptest.NewServer(g.Handler)
+	t.Cleanup(func() {
+		cancel()
+		ts.Close()
+		_ = g.ws.Close()
+		_ = g.ln.Close()
+	})
+	return g, "ws" + strings.TrimPrefix(ts.URL, "http") + "/ws"
+}
+
+func TestHeartbeatHandledByGate(t *testing.T) {
+	g, url := startTestGate(t, HeartbeatCmd(99))
+
+	conn, _, err := websocket.DefaultDialer.Dial(url+"?uid=42", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	raw, err := proto.Marshal(&envelope.IMessage{Header: &envelope.Header{Seq: 1, Cmd: 99, Timestamp: 1}})
+	require.NoError(t, err)
+	require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, raw))
+
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, data, err := conn.ReadMessage()
+	require.NoError(t, err)
+	out := &envelope.OMessage{}
+	require.NoError(t, proto.Unmarshal(data, out))
+	require.Equal(t, uint64(1), out.GetHeader().GetSeq())
+	require.Equal(t, uint32(99), out.GetHeader().GetCmd())
+	require.Equal(t, "gate", out.GetService())
+	require.InDelta(t, time.Now().UnixMilli(), out.GetHeader().GetTimestamp(), 1000)
+
+	bro := g.opts.broker.(*testBroker)
+	bro.mu.Lock()
+	defer bro.mu.Unlock()
+	require.Equal(t, 0, bro.pubCalls)
+}
+
+func TestHeartbeatOptIn(t *testing.T) {
+	msg := &envelope.IMessage{Header: &envelope.Header{Cmd: 0}}
+	require.False(t, New().isHeartbeat(msg))
+	require.True(t, New(HeartbeatCmd(0)).isHeartbeat(msg))
+}
+
+func TestIdleSessionEvicted(t *testing.T) {
+	g, url := startTestGate(t, IdleTimeout(200*time.Millisecond), HeartbeatCmd(0))
+	go g.evictIdle()
+
+	conn, _, err := websocket.DefaultDialer.Dial(url+"?uid=42", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.Eventually(t, func() bool {
+		_, ok := g.sessions.get(42)
+		return ok
+	}, time.Second, 20*time.Millisecond)
+
+	// 心跳不计入业务活跃
+	raw, err := proto.Marshal(&envelope.IMessage{Header: &envelope.Header{Cmd: 0}})
+	require.NoError(t, err)
+	require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, raw))
+
+	require.Eventually(t, func() bool {
+		_, ok := g.sessions.get(42)
+		return !ok
+	}, 2*time.Second, 20*time.Millisecond)
+
+	loc := g.opts.locator.(*testLocator)
+	loc.mu.Lock()
+	defer loc.mu.Unlock()
+	require.Equal(t, 1, loc.unbindCalls)
+}
+
+func TestOutboxSlowConsumerPolicy(t *testing.T) {
+	msg := func(seq uint64, typ envelope.MsgType) []byte {
+		data, err := proto.Marshal(&envelope.OMessage{Header: &envelope.Header{Seq: seq}, MsgType: typ})
+		require.NoError(t, err)
+		return data
+	}
+	seqs := func(ob *outbox) []uint64 {
+		var res []uint64
+		for _, data := range ob.queue {
+			out := &envelope.OMessage{}
+			require.NoError(t, proto.Unmarshal(data, out))
+			res = append(res, out.GetHeader().GetSeq())
+		}
+		return res
+	}
+
+	// 优先丢弃推送, 保留响应
+	ob := newOutbox(2, 1, DropPush)
+	require.True(t, ob.push(msg(1, envelope.MsgType_RESPONSE)))
+	require.True(t, ob.push(msg(2, envelope.MsgType_PUSH)))
+	require.True(t, ob.push(msg(3, envelope.MsgType_PUSH)))
+	require.Equal(t, []uint64{1, 2}, seqs(ob))
+	require.True(t, ob.push(msg(4, envelope.MsgType_RESPONSE)))
+	require.Equal(t, []uint64{1, 4}, seqs(ob))
+
+	ob = newOutbox(2, 1, DropOldest)
+	for i := uint64(1); i <= 3; i++ {
+		require.True(t, ob.push(msg(i, envelope.MsgType_PUSH)))
+	}
+	require.Equal(t, []uint64{2, 3}, seqs(ob))
+
+	ob = newOutbox(1, 1, Disconnect)
+	require.True(t, ob.push(msg(1, envelope.MsgType_PUSH)))
+	require.False(t, ob.push(msg(2, envelope.MsgType_PUSH)))
+}
+
+func TestOutboxBatchFrame(t *testing.T) {
+	g, url := startTestGate(t)
+
+	conn, _, err := websocket.DefaultDialer.Dial(url+"?uid=42&batch=true", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.Eventually(t, func() bool {
+		_, ok := g.sessions.get(42)
+		return ok
+	}, time.Second, 20*time.Millisecond)
+
+	for i := uint64(1); i <= 3; i++ {
+		data, err := proto.Marshal(&envelope.OMessage{Header: &envelope.Header{Seq: i}, MsgType: envelope.MsgType_RESPONSE})
+		require.NoError(t, err)
+		g.handlePubSubMessage(&broker.Message{
+			Header: cluster.BuildHeader(42, cluster.Event_Business, "", "game", "gate"),
+			Data:   data,
+		})
+	}
+
+	var got []uint64
+	for len(got) < 3 {
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+		_, frame, err := conn.ReadMessage()
+		require.NoError(t, err)
+		batch := &envelope.OBatch{}
+		require.NoError(t, proto.Unmarshal(frame, batch))
+		for _, m := range batch.GetMessages() {
+			got = append(got, m.GetHeader().GetSeq())
+		}
+	}
+	require.Equal(t, []uint64{1, 2, 3}, got)
+}
+
+func TestHandleMessageRecoversPanic(t *testing.T) {
+	g := New(Broker(&testBroker{}))
+	// nil 会话使写出时触发异常
+	g.sessions.register(42, nil)
+
+	msg := &broker.Message{Header: cluster.BuildHeader(42, cluster.Event_Business, "", "game", "gate")}
+	require.NotPanics(t, func() { g.handleMessage(msg) })
+	require.Equal(t, uint64(1), g.Panics())
+}
+
+func TestMetadataForwardedToMesh(t *testing.T) {
+	g, url := startTestGate(t,
+		Locator(&testLocator{node: "game-1"}),
+		MetadataExtractor(func(r *http.Request, uid int64) metadata.Metadata {
+			return metadata.New("role", r.FormValue("role"))
+		}),
+	)
+
+	conn, _, err := websocket.DefaultDialer.Dial(url+"?uid=42&role=vip", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	raw, err := proto.Marshal(&envelope.IMessage{Header: &envelope.Header{Seq: 1, Cmd: 5}, Service: "game"})
+	require.NoError(t, err)
+	require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, raw))
+
+	bro := g.opts.broker.(*testBroker)
+	require.Eventually(t, func() bool {
+		bro.mu.Lock()
+		defer bro.mu.Unlock()
+		return bro.pubCalls == 1
+	}, time.Second, 10*time.Millisecond)
+
+	bro.mu.Lock()
+	defer bro.mu.Unlock()
+	require.Equal(t, "vip", cluster.GetMetadataBy(bro.pubHeader).Get("role"))
+}
+
+func TestBroadcastEventCarriesNodeAndMetadata(t *testing.T) {
+	bro := &testBroker{}
+	g := New(Broker(bro), Locator(&testLocator{nodes: map[string]string{"gate": "gate-1", "game": "game-1"}}))
+	g.ctx, g.appName, g.appID = context.Background(), "gate", "gate-1"
+
+	g.broadcastEvent(42, cluster.Event_Offline, metadata.New("role", "vip"))
+
+	bro.mu.Lock()
+	defer bro.mu.Unlock()
+	require.Equal(t, 1, bro.pubCalls)
+	require.Equal(t, cluster.Subject(g.opts.prefix, "gate", "game", "game-1"), bro.pubSubject)
+	require.Equal(t, cluster.Event_Offline, cluster.GetEventBy(bro.pubHeader))
+	require.Equal(t, "gate-1", cluster.GetFromNodeBy(bro.pubHeader))
+	require.Equal(t, "vip", cluster.GetMetadataBy(bro.pubHeader).Get("role"))
+}
+
+func TestStatelessServiceDispatch(t *testing.T) {
+	g, url := startTestGate(t, StatelessServices("mail"))
+
+	conn, _, err := websocket.DefaultDialer.Dial(url+"?uid=42", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	raw, err := proto.Marshal(&envelope.IMessage{Header: &envelope.Header{Seq: 1, Cmd: 5}, Service: "mail"})
+	require.NoError(t, err)
+	require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, raw))
+
+	bro := g.opts.broker.(*testBroker)
+	require.Eventually(t, func() bool {
+		bro.mu.Lock()
+		defer bro.mu.Unlock()
+		return bro.pubCalls == 1
+	}, time.Second, 10*time.Millisecond)
+
+	bro.mu.Lock()
+	defer bro.mu.Unlock()
+	require.Equal(t, cluster.ServiceSubject(g.opts.prefix, g.appName, "mail"), bro.pubSubject)
+}
+
+func TestPauseBuffersDispatchUntilResume(t *testing.T) {
+	bro := &testBroker{}
+	g := New(
+		Locator(&testLocator{node: "game-1"}),
+		Broker(bro),
+	)
+	g.ctx, g.appName, g.appID = context.Background(), "gate", "gate-1"
+
+	control := func(event cluster.Event) {
+		g.handleMessage(&broker.Message{
+			Reply:  "game.reply",
+			Header: cluster.BuildHeader(42, event, "", "game", "gate"),
+		})
+	}
+	control(cluster.Event_Pause)
+
+	e := &envelope.IMessage{Header: &envelope.Header{Seq: 1, Cmd: 5}, Service: "game"}
+	g.dispatch(42, e, nil)
+	g.dispatch(42, &envelope.IMessage{Header: &envelope.Header{Seq: 1, Cmd: 5}, Service: "chat"}, nil)
+
+	bro.mu.Lock()
+	require.Equal(t, 1, bro.pubCalls)
+	require.Equal(t, 1, bro.replyCalls)
+	require.Equal(t, "200", bro.replyHeader.Get("code"))
+	bro.mu.Unlock()
+
+	control(cluster.Event_Resume)
+
+	bro.mu.Lock()
+	defer bro.mu.Unlock()
+	require.Equal(t, 2, bro.pubCalls)
+	require.Equal(t, cluster.Subject(g.opts.prefix, "gate", "game", "game-1"), bro.pubSubject)
+}
+
+// blockingBroker 记录发布消息的 seq, 首次发布时阻塞至 release 关闭
+type blockingBroker struct {
+	testBroker
+	started chan struct{}
+	release chan struct{}
+	seqs    []uint64
+}
+
+func (b *blockingBroker) Pub(ctx context.Context, subject string, data []byte, opts ...broker.PublishOption) error {
+	e := &envelope.IMessage{}
+	if err := proto.Unmarshal(data, e); err != nil {
+		return err
+	}
+	b.mu.Lock()
+	b.seqs = append(b.seqs, e.GetHeader().GetSeq())
+	first := len(b.seqs) == 1
+	b.mu.Unlock()
+	if first {
+		close(b.started)
+		<-b.release
+	}
+	return b.testBroker.Pub(ctx, subject, data, opts...)
+}
+
+func TestResumeOrdersConcurrentTraffic(t *testing.T) {
+	bro := &blockingBroker{started: make(chan struct{}), release: make(chan struct{})}
+	g := New(Locator(&testLocator{node: "game-1"}), Broker(bro))
+	g.ctx, g.appName, g.appID = context.Background(), "gate", "gate-1"
+
+	msg := func(seq uint64) *envelope.IMessage {
+		return &envelope.IMessage{Header: &envelope.Header{Seq: seq, Cmd: 5}, Service: "game"}
+	}
+	g.pause(42, "game", time.Minute)
+	g.dispatch(42, msg(1), nil)
+	g.dispatch(42, msg(2), nil)
+
+	resumed := make(chan struct{})
+	go func() {
+		defer close(resumed)
+		g.resume(42, "game")
+	}()
+	<-bro.started // 正在投递缓存的消息
+
+	// 投递期间到达的新消息须排在缓存消息之后
+	dispatched := make(chan struct{})
+	go func() {
+		defer close(dispatched)
+		g.dispatch(42, msg(3), nil)
+	}()
+	select {
+	case <-dispatched:
+		t.Fatal("new message dispatched during replay")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(bro.release)
+	<-resumed
+	<-dispatched
+
+	bro.mu.Lock()
+	defer bro.mu.Unlock()
+	require.Equal(t, []uint64{1, 2, 3}, bro.seqs)
+	_, paused := g.paused.Load(pauseKey{uid: 42, service: "game"})
+	require.False(t, paused)
+}
+
+func TestPauseDeadlineAndOverflow(t *testing.T) {
+	g, url := startTestGate(t, MessageBufferSize(1), Locator(&testLocator{node: "game-1"}))
+	bro := g.opts.broker.(*testBroker)
+
+	conn, _, err := websocket.DefaultDialer.Dial(url+"?uid=42", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.Eventually(t, func() bool {
+		_, ok := g.sessions.get(42)
+		return ok
+	}, time.Second, 5*time.Millisecond)
+
+	// 暂停至迁移截止时间
+	header := cluster.BuildHeader(42, cluster.Event_Pause, "", "game", "gate")
+	cluster.SetDeadline(header, time.Now().Add(200*time.Millisecond))
+	g.handleMessage(&broker.Message{Reply: "game.reply", Header: header})
+
+	send := func(seq uint64) {
+		raw, err := proto.Marshal(&envelope.IMessage{Header: &envelope.Header{Seq: seq, Cmd: 5}, Service: "game"})
+		require.NoError(t, err)
+		require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, raw))
+	}
+	send(1)
+	send(2)
+
+	// 缓冲已满, 回复客户端 503
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, data, err := conn.ReadMessage()
+	require.NoError(t, err)
+	out := &envelope.OMessage{}
+	require.NoError(t, proto.Unmarshal(data, out))
+	require.EqualValues(t, http.StatusServiceUnavailable, out.GetResult().GetCode())
+	require.EqualValues(t, 2, out.GetHeader().GetSeq())
+
+	// 截止时间到达后自动恢复, 投递缓存的消息
+	bro.mu.Lock()
+	require.Equal(t, 0, bro.pubCalls)
+	bro.mu.Unlock()
+	require.Eventually(t, func() bool {
+		bro.mu.Lock()
+		defer bro.mu.Unlock()
+		return bro.pubCalls == 1
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestDispatchUsesRouteDigest(t *testing.T) {
+	g, url := startTestGate(t)
+
+	sel := &testSelector{}
+	sel.Update([]selector.Node{
+		selector.NewNode("game-1", "game", "v1", map[string]string{cluster.MetadataKey_Routes: "1.1"}),
+		selector.NewNode("game-2", "game", "v2", map[string]string{cluster.MetadataKey_Routes: "1.1,5.2"}),
+	})
+	g.selectors.Store("game", sel)
+
+	conn, _, err := websocket.DefaultDialer.Dial(url+"?uid=42", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	bro := g.opts.broker.(*testBroker)
+	send := func(seq uint64, cmd, version uint32) {
+		raw, err := proto.Marshal(&envelope.IMessage{Header: &envelope.Header{Seq: seq, Cmd: cmd, Version: version}, Service: "game"})
+		require.NoError(t, err)
+		require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, raw))
+	}
+
+	// 只有 game-2 实现了 5.2
+	send(1, 5, 2)
+	require.Eventually(t, func() bool {
+		bro.mu.Lock()
+		defer bro.mu.Unlock()
+		return bro.pubCalls == 1
+	}, time.Second, 10*time.Millisecond)
+	bro.mu.Lock()
+	require.Equal(t, cluster.Subject(g.opts.prefix, "gate", "game", "game-2"), bro.pubSubject)
+	bro.mu.Unlock()
+
+	// 没有节点实现 9.1, 网关直接回复 404
+	send(2, 9, 1)
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, data, err := conn.ReadMessage()
+	require.NoError(t, err)
+	out := &envelope.OMessage{}
+	require.NoError(t, proto.Unmarshal(data, out))
+	require.EqualValues(t, http.StatusNotFound, out.GetResult().GetCode())
+	require.EqualValues(t, 2, out.GetHeader().GetSeq())
+}
+
+// memBroker 进程内 broker, 按主题(支持 * 通配)同步投递给订阅者, 用于 gate 与 mesh 联调
+type memBroker struct {
+	testBroker
+	subs sync.Map // key: 订阅主题, value: broker.Handler
+}
+
+func (b *memBroker) Pub(_ context.Context, subject string, data []byte, opts ...broker.PublishOption) error {
+	pubOpts := &broker.PublishOptions{}
+	for _, opt := range opts {
+		opt(pubOpts)
+	}
+	b.subs.Range(func(k, v any) bool {
+		if matchSubject(k.(string), subject) {
+			v.(broker.Handler)(&broker.Message{Subject: subject, Header: pubOpts.Header, Data: data})
+		}
+		return true
+	})
+	return nil
+}
+
+func (b *memBroker) Sub(_ context.Context, subject string, handler broker.Handler, _ ...broker.SubscribeOption) (broker.Subscription, error) {
+	b.subs.Store(subject, handler)
+	return &testSubscription{}, nil
+}
+
+func matchSubject(pattern, subject string) bool {
+	ps, ss := strings.Split(pattern, "."), strings.Split(subject, ".")
+	if len(ps) != len(ss) {
+		return false
+	}
+	for i := range ps {
+		if ps[i] != "*" && ps[i] != ss[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// TestRouteDigestWithMeshVersions 验证 gate 依据 mesh 发布的路由摘要转发版本回退与升级的请求
+func TestRouteDigestWithMeshVersions(t *testing.T) {
+	bro := &memBroker{}
+
+	// mesh: 5 仅注册 v1 并开启回退, 7 注册 v2 与 1 -> 2 的升级
+	m := mesh.New(mesh.Broker(bro), mesh.Locator(&testLocator{}), mesh.PublishRoutes(true))
+	reply := func(version uint32) func(*mesh.Context, *envelope.Header) {
+		return func(ctx *mesh.Context, _ *envelope.Header) {
+			ctx.OkResp(&envelope.Header{Version: version})
+		}
+	}
+	m.RouteX(5, 1, reply(1))
+	m.SetVersionPolicy(5, mesh.VersionFallback)
+	m.RouteX(7, 2, reply(2))
+	m.Upgrade(7, 1, 2, func(p []byte) ([]byte, error) { return p, nil })
+
+	meshCtx, cancel := context.WithCancel(meta.NewContext(context.Background(), testAppInfo{id: "game-1", name: "game"}))
+	t.Cleanup(cancel)
+	go func() { _ = m.Start(meshCtx) }()
+	require.Eventually(t, func() bool {
+		_, ok := bro.subs.Load(cluster.Subject("meta", "*", "game", "game-1"))
+		return ok
+	}, time.Second, 5*time.Millisecond)
+
+	// gate: 节点元数据为 mesh 注册到服务发现的元数据
+	g, url := startTestGate(t, Broker(bro))
+	require.NoError(t, g.loop())
+	sel := &testSelector{}
+	sel.Update([]selector.Node{selector.NewNode("game-1", "game", "v1", m.Metadata())})
+	g.selectors.Store("game", sel)
+
+	conn, _, err := websocket.DefaultDialer.Dial(url+"?uid=42", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	send := func(seq uint64, cmd, version uint32) {
+		raw, err := proto.Marshal(&envelope.IMessage{Header: &envelope.Header{Seq: seq, Cmd: cmd, Version: version}, Service: "game"})
+		require.NoError(t, err)
+		require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, raw))
+	}
+	send(1, 5, 3) // 回退到 v1
+	send(2, 7, 1) // 升级到 v2
+	send(3, 5, 0) // 低于最低版本, 网关直接回复 404
+
+	results := make(map[uint64]*envelope.OMessage)
+	for range 3 {
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+		_, data, err := conn.ReadMessage()
+		require.NoError(t, err)
+		out := &envelope.OMessage{}
+		require.NoError(t, proto.Unmarshal(data, out))
+		results[out.GetHeader().GetSeq()] = out
+	}
+	for seq, version := range map[uint64]uint32{1: 1, 2: 2} {
+		out := results[seq]
+		require.NotNil(t, out, "seq %d", seq)
+		require.Nil(t, out.GetResult(), "seq %d", seq)
+		payload := &envelope.Header{}
+		require.NoError(t, proto.Unmarshal(out.GetPayload(), payload))
+		require.Equal(t, version, payload.GetVersion(), "seq %d", seq)
+	}
+	require.NotNil(t, results[3])
+	require.EqualValues(t, http.StatusNotFound, results[3].GetResult().GetCode())
+}
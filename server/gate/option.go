@@ -1,6 +1,7 @@
 package gate
 
 import (
+	"crypto/ed25519"
 	"net/http"
 	"time"
 
@@ -8,6 +9,7 @@ import (
 	"github.com/byteweap/meta/component/locator"
 	"github.com/byteweap/meta/component/registry"
 	"github.com/byteweap/meta/component/selector"
+	"github.com/byteweap/meta/metadata"
 	"github.com/byteweap/meta/pkg/conv"
 )
 
@@ -20,33 +22,61 @@ const (
 	defaultPingInterval      = 10 * time.Second
 	defaultMaxMessageSize    = 1024 * 2
 	defaultMessageBufferSize = 256
+	defaultOutboundQueueSize = 256
+	defaultMaxBatchSize      = 32
+	defaultSeqStateTTL       = 30 * time.Second
 )
 
 // IdExtractor 用户id提取器
 // gate 会在建立连接时调用此函数获取用户id
 type IdExtractor func(r *http.Request) int64
 
+// MetaExtractor 元数据提取器
+// gate 会在建立连接时调用此函数, 提取的元数据(如令牌中的用户声明)随该连接的每条业务消息透传至 mesh
+type MetaExtractor func(r *http.Request, uid int64) metadata.Metadata
+
 // options 选项
 type options struct {
 
 	// app
-	prefix          string      // subject / redis key 前缀
-	userIdExtractor IdExtractor // 用户 id 提取器
+	prefix          string        // subject / redis key 前缀
+	userIdExtractor IdExtractor   // 用户 id 提取器
+	mdExtractor     MetaExtractor // 元数据提取器
 
 	// websocket
-	path              string        // ws 路径
-	addr              string        // ws 地址
-	writeTimeout      time.Duration // write 超时时间
-	pongTimeout       time.Duration // Pong 超时时间
-	pingInterval      time.Duration // Ping 间隔时间
-	maxMessageSize    int64         // 最大消息大小
-	messageBufferSize int           // 消息缓冲区大小, websocket 和 broker 都用
+	path              string             // ws 路径
+	addr              string             // ws 地址
+	writeTimeout      time.Duration      // write 超时时间
+	pongTimeout       time.Duration      // Pong 超时时间
+	pingInterval      time.Duration      // Ping 间隔时间
+	maxMessageSize    int64              // 最大消息大小
+	messageBufferSize int                // 消息缓冲区大小, websocket 和 broker 都用
+	identity          ed25519.PrivateKey // 网关身份私钥, 用于端到端加密握手签名, nil 表示不加密
+	seqPolicy         SeqPolicy          // 上行消息 seq 校验策略
+	respCacheSize     int                // 每个会话缓存的最近响应数量, 0 表示不缓存
+	seqStateTTL       time.Duration      // 断线后保留 seq 状态与响应缓存的时长
+	heartbeat         bool               // 是否由网关处理心跳
+	heartbeatCmd      uint32             // 心跳指令
+	idleTimeout       time.Duration      // 空闲超时时间, 超时未收到业务消息则断开连接, 0 表示不检测
+
+	// outbound
+	outboundQueueSize  int                // 每个会话的下行队列上限
+	maxBatchSize       int                // 批量帧最多合并的消息数
+	slowConsumerPolicy SlowConsumerPolicy // 下行队列超出上限时的策略
+
+	// admin
+	adminAddr       string                          // 管理接口地址, 为空表示不开启
+	adminToken      string                          // 管理接口 Bearer 令牌, 为空表示不校验
+	adminMiddleware func(http.Handler) http.Handler // 管理接口中间件
 
 	// component
 	locator      locator.Locator          // 玩家位置定位器
 	broker       broker.Broker            // 消息传输代理
 	discovery    registry.Registry        // 服务发现
 	selectorFunc func() selector.Selector // 选择器创建函数
+
+	// route
+	stateless map[string]struct{} // 无状态服务, 发布到服务级主题
 }
 
 type Option func(*options)
@@ -61,6 +91,9 @@ func defaultOptions() *options {
 		pingInterval:      defaultPingInterval,
 		maxMessageSize:    defaultMaxMessageSize, // 2k
 		messageBufferSize: defaultMessageBufferSize,
+		outboundQueueSize: defaultOutboundQueueSize,
+		maxBatchSize:      defaultMaxBatchSize,
+		seqStateTTL:       defaultSeqStateTTL,
 		userIdExtractor: func(r *http.Request) int64 {
 			return conv.Int64(r.FormValue("uid"))
 		},
@@ -139,6 +172,134 @@ func MessageBufferSize(size int) Option {
 	}
 }
 
+// Secure 以网关身份私钥开启端到端加密, 默认: 关闭(nil)
+// 开启后客户端须在握手时通过请求头 X-Meta-Key 或 query 参数 key 携带 X25519 公钥(base64url),
+// 网关以首个二进制帧回复 secure.ServerHello 握手消息(临时公钥 + 身份私钥签名), 此后所有帧均按 pkg/secure 的格式加密
+// 客户端须预置身份公钥并通过 secure.OpenServerHello 校验签名, 以防中间人替换公钥; 所有网关节点应使用同一身份私钥
+//
+// 示例:
+//
+//	identity := ed25519.NewKeyFromSeed(seed) // seed 从配置或密钥管理服务读取
+//	gate.New(gate.Secure(identity))
+func Secure(identity ed25519.PrivateKey) Option {
+	return func(o *options) {
+		o.identity = identity
+	}
+}
+
+// SeqValidation 设置上行消息 seq 校验策略, 默认: SeqNone
+// 开启后网关跟踪每个会话最近受理的 seq, 重复或乱序(不大于已受理 seq)的消息按策略丢弃或拒绝
+func SeqValidation(policy SeqPolicy) Option {
+	return func(o *options) {
+		o.seqPolicy = policy
+	}
+}
+
+// ResponseCache 设置每个会话按 seq 缓存的最近响应数量, 默认: 0(不缓存)
+// 客户端超时重发已处理的请求时, 网关直接返回缓存的响应而不会再次转发至 mesh
+// SeqNone 策略下仅命中缓存的请求被拦截, 其余请求照常转发
+func ResponseCache(size int) Option {
+	return func(o *options) {
+		if size >= 0 {
+			o.respCacheSize = size
+		}
+	}
+}
+
+// SeqStateTTL 设置断线后保留 seq 状态与响应缓存的时长, 默认: 30s
+// 玩家在此期间重连时沿用原状态, 重连后重发的请求仍可命中响应缓存
+func SeqStateTTL(d time.Duration) Option {
+	return func(o *options) {
+		if d > 0 {
+			o.seqStateTTL = d
+		}
+	}
+}
+
+// HeartbeatCmd 开启网关心跳并设置心跳指令, 默认: 不开启(所有消息均转发至 mesh)
+// service 为空(或为网关自身)且 cmd 为心跳指令的消息由网关直接回复, 不会转发至 mesh,
+// 响应头 timestamp 为服务器当前时间(ms), 可用于客户端校时
+func HeartbeatCmd(cmd uint32) Option {
+	return func(o *options) {
+		o.heartbeat = true
+		o.heartbeatCmd = cmd
+	}
+}
+
+// IdleTimeout 设置空闲超时时间, 默认: 0(不检测)
+// 超过该时间未收到业务消息(心跳不计入)的会话将被断开, 并照常广播掉线事件
+func IdleTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		if timeout >= 0 {
+			o.idleTimeout = timeout
+		}
+	}
+}
+
+// OutboundQueueSize 设置每个会话的下行队列上限, 默认: 256
+// 客户端消费缓慢导致队列超出上限时按 SlowConsumer 策略处理, 不会阻塞网关主循环
+func OutboundQueueSize(size int) Option {
+	return func(o *options) {
+		if size > 0 {
+			o.outboundQueueSize = size
+		}
+	}
+}
+
+// MaxBatchSize 设置批量帧最多合并的消息数, 默认: 32
+// 仅对握手时通过请求头 X-Meta-Batch 或 query 参数 batch 声明支持批量的客户端生效,
+// 这类客户端收到的每一帧均为 envelope.OBatch
+func MaxBatchSize(size int) Option {
+	return func(o *options) {
+		if size > 0 {
+			o.maxBatchSize = size
+		}
+	}
+}
+
+// SlowConsumer 设置下行队列超出上限时的策略, 默认: DropPush
+func SlowConsumer(policy SlowConsumerPolicy) Option {
+	return func(o *options) {
+		o.slowConsumerPolicy = policy
+	}
+}
+
+// AdminAddr 设置管理接口(HTTP)地址, 默认: 不开启
+// 提供会话查询、踢人、服务节点与监听状态查看等接口; 未指定主机时(如 ":9100")仅监听 127.0.0.1,
+// 需对外暴露时显式指定(如 "0.0.0.0:9100")并通过 AdminToken 或 AdminMiddleware 开启鉴权
+func AdminAddr(addr string) Option {
+	return func(o *options) {
+		o.adminAddr = addr
+	}
+}
+
+// AdminToken 设置管理接口 Bearer 令牌, 默认: 不校验
+// 开启后请求须携带请求头 Authorization: Bearer <token>, 否则回复 401
+func AdminToken(token string) Option {
+	return func(o *options) {
+		o.adminToken = token
+	}
+}
+
+// AdminMiddleware 设置管理接口中间件, 用于自定义鉴权、审计等, 在 AdminToken 校验通过后执行
+//
+// 示例:
+//
+//	gate.AdminMiddleware(func(next http.Handler) http.Handler {
+//		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+//			if !allowed(r) {
+//				http.Error(w, "forbidden", http.StatusForbidden)
+//				return
+//			}
+//			next.ServeHTTP(w, r)
+//		})
+//	})
+func AdminMiddleware(mw func(http.Handler) http.Handler) Option {
+	return func(o *options) {
+		o.adminMiddleware = mw
+	}
+}
+
 // UserIdExtractor 设置用户 id 提取器
 // gate 会在建立连接时调用此函数获取用户id, 默认: func(r *http.Request) int64 { return conv.Int64(r.FormValue("uid")) }
 func UserIdExtractor(extractor IdExtractor) Option {
@@ -149,6 +310,15 @@ func UserIdExtractor(extractor IdExtractor) Option {
 	}
 }
 
+// MetadataExtractor 设置元数据提取器, 默认不提取
+func MetadataExtractor(extractor MetaExtractor) Option {
+	return func(o *options) {
+		if extractor != nil {
+			o.mdExtractor = extractor
+		}
+	}
+}
+
 // Locator 设置玩家位置定位器
 func Locator(locator locator.Locator) Option {
 	return func(o *options) {
@@ -184,3 +354,19 @@ func SelectorFunc(selectorFunc func() selector.Selector) Option {
 		}
 	}
 }
+
+// StatelessServices 设置无状态服务列表
+// 发往这些服务的消息发布到服务级主题, 由 broker 以 Queue Group 负载均衡到任一节点,
+// 无需玩家绑定与选择器; 目标服务须以 mesh.SubscribeService 模式订阅
+func StatelessServices(services ...string) Option {
+	return func(o *options) {
+		if o.stateless == nil {
+			o.stateless = make(map[string]struct{}, len(services))
+		}
+		for _, service := range services {
+			if service != "" {
+				o.stateless[service] = struct{}{}
+			}
+		}
+	}
+}
@@ -32,3 +32,20 @@ func (ss *Sessions) get(uid int64) (*melody.Session, bool) {
 	}
 	return nil, false
 }
+
+// count 会话数量
+func (ss *Sessions) count() int {
+	n := 0
+	ss.data.Range(func(_, _ any) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+// rangeAll 遍历所有会话, fn 返回 false 时停止遍历
+func (ss *Sessions) rangeAll(fn func(uid int64, s *melody.Session) bool) {
+	ss.data.Range(func(key, value any) bool {
+		return fn(key.(int64), value.(*melody.Session))
+	})
+}
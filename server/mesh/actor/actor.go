@@ -0,0 +1,32 @@
+package actor
+
+import "errors"
+
+var (
+	ErrStopped     = errors.New("actor stopped")
+	ErrMailboxFull = errors.New("actor mailbox full")
+	ErrNoProducer  = errors.New("actor producer required")
+)
+
+// Actor 业务 actor
+// 同一 actor 的所有消息在其独立的 goroutine 中按投递顺序串行执行,
+// 因此 actor 内部状态无需加锁
+type Actor interface {
+	Receive(ctx *Context, msg any)
+}
+
+// Starter actor 启动回调, 在处理第一条消息前调用
+type Starter interface {
+	OnStart(ctx *Context)
+}
+
+// Stopper actor 停止回调, 在处理完剩余消息后调用
+type Stopper interface {
+	OnStop(ctx *Context)
+}
+
+// Producer 按 id 创建 actor
+type Producer func(id int64) Actor
+
+// Func 在 actor goroutine 中执行的函数
+type Func func(ctx *Context)
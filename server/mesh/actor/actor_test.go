@@ -0,0 +1,173 @@
+package actor
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/byteweap/meta/envelope"
+	"github.com/byteweap/meta/server/mesh"
+)
+
+type counter struct {
+	mu      sync.Mutex
+	got     []int
+	started atomic.Bool
+	stopped atomic.Bool
+}
+
+func (c *counter) Receive(_ *Context, msg any) {
+	if n, ok := msg.(int); ok {
+		c.mu.Lock()
+		c.got = append(c.got, n)
+		c.mu.Unlock()
+	}
+}
+
+func (c *counter) OnStart(*Context) { c.started.Store(true) }
+func (c *counter) OnStop(*Context)  { c.stopped.Store(true) }
+
+func (c *counter) values() []int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]int(nil), c.got...)
+}
+
+func eventually(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("condition not met")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// TestSystemOrderingAndStop 验证消息按投递顺序处理, 停止时先处理剩余消息再调用停止回调
+func TestSystemOrderingAndStop(t *testing.T) {
+	actors := map[int64]*counter{}
+	var mu sync.Mutex
+	sys := NewSystem(func(id int64) Actor {
+		mu.Lock()
+		defer mu.Unlock()
+		actors[id] = &counter{}
+		return actors[id]
+	})
+
+	for i := range 100 {
+		if err := sys.Tell(1, i); err != nil {
+			t.Fatalf("tell: %v", err)
+		}
+	}
+	sys.Stop(1)
+
+	c := actors[1]
+	got := c.values()
+	if len(got) != 100 {
+		t.Fatalf("expected 100 messages, got %d", len(got))
+	}
+	for i, n := range got {
+		if n != i {
+			t.Fatalf("out of order at %d: %d", i, n)
+		}
+	}
+	if !c.started.Load() || !c.stopped.Load() {
+		t.Fatalf("lifecycle hooks not called")
+	}
+	if sys.Len() != 0 {
+		t.Fatalf("stopped actor not removed")
+	}
+	sys.Shutdown()
+	if err := sys.Tell(1, 0); err != ErrStopped {
+		t.Fatalf("expected ErrStopped after shutdown, got %v", err)
+	}
+}
+
+// TestTimers 验证 actor 内定时器投递与取消
+func TestTimers(t *testing.T) {
+	c := &counter{}
+	sys := NewSystem(func(int64) Actor { return c })
+	defer sys.Shutdown()
+
+	_ = sys.Exec(1, func(a *Context) {
+		a.After(10*time.Millisecond, 7)
+		a.After(10*time.Millisecond, 8).Stop()
+	})
+	eventually(t, func() bool { return len(c.values()) == 1 })
+	time.Sleep(20 * time.Millisecond)
+	if got := c.values(); len(got) != 1 || got[0] != 7 {
+		t.Fatalf("unexpected timer messages: %v", got)
+	}
+
+	var tick *Timer
+	_ = sys.Exec(1, func(a *Context) {
+		tick = a.Every(5*time.Millisecond, 9)
+	})
+	eventually(t, func() bool { return len(c.values()) >= 3 })
+
+	// Every 定时器在 actor 内停止后不再投递
+	stopped := make(chan int, 1)
+	_ = sys.Exec(1, func(*Context) {
+		tick.Stop()
+		stopped <- len(c.values())
+	})
+	n := <-stopped
+	time.Sleep(20 * time.Millisecond)
+	if got := len(c.values()); got != n {
+		t.Fatalf("timer not stopped: %d -> %d", n, got)
+	}
+}
+
+// TestPassivation 验证空闲 actor 被回收, 再次投递时重新创建
+func TestPassivation(t *testing.T) {
+	var created atomic.Int32
+	var last *counter
+	sys := NewSystem(func(int64) Actor {
+		created.Add(1)
+		last = &counter{}
+		return last
+	}, IdleTimeout(20*time.Millisecond))
+	defer sys.Shutdown()
+
+	_ = sys.Tell(1, 1)
+	eventually(t, func() bool { return sys.Len() == 0 })
+	if !last.stopped.Load() {
+		t.Fatalf("passivated actor not stopped")
+	}
+	_ = sys.Tell(1, 2)
+	eventually(t, func() bool { return created.Load() == 2 })
+}
+
+// TestPanicRecovered 验证单条消息异常不影响 actor 继续运行
+func TestPanicRecovered(t *testing.T) {
+	c := &counter{}
+	sys := NewSystem(func(int64) Actor { return c }, MailboxSize(4))
+	defer sys.Shutdown()
+
+	_ = sys.Exec(1, func(*Context) { panic("boom") })
+	_ = sys.Tell(1, 1)
+	eventually(t, func() bool { return len(c.values()) == 1 })
+	if sys.Panics() != 1 {
+		t.Fatalf("unexpected panics: %d", sys.Panics())
+	}
+}
+
+// TestRoute 验证 mesh 路由消息转发到 actor
+func TestRoute(t *testing.T) {
+	sys := NewSystem(func(int64) Actor { return &counter{} })
+	defer sys.Shutdown()
+
+	done := make(chan int64, 1)
+	handler := Route(sys, ByUid, func(a *Context, ctx *mesh.Context, req *envelope.Header) {
+		done <- a.ID()
+	})
+	m := mesh.New()
+	m.Route(1, 1, mesh.Wrap(handler))
+
+	handler(&mesh.Context{}, &envelope.Header{})
+	if id := <-done; id != 0 {
+		t.Fatalf("unexpected actor id: %d", id)
+	}
+}
@@ -0,0 +1,62 @@
+package actor
+
+import (
+	"time"
+)
+
+// Context actor 上下文, 仅可在 actor goroutine 中使用
+type Context struct {
+	proc *process
+}
+
+// ID 返回 actor id
+func (c *Context) ID() int64 {
+	return c.proc.id
+}
+
+// Actor 返回 actor 实例
+func (c *Context) Actor() Actor {
+	return c.proc.actor
+}
+
+// Self 返回 actor 自身引用
+func (c *Context) Self() *Ref {
+	return c.proc.ref()
+}
+
+// System 返回 actor 所属 System
+func (c *Context) System() *System {
+	return c.proc.sys
+}
+
+// Stop 处理完当前消息后停止 actor
+func (c *Context) Stop() {
+	c.proc.stopping = true
+}
+
+// After 在 d 之后向自身投递消息 msg
+// 定时器在 actor 停止时自动取消
+func (c *Context) After(d time.Duration, msg any) *Timer {
+	return c.proc.schedule(d, false, msg)
+}
+
+// Every 每隔 d 向自身投递消息 msg, 直到定时器停止或 actor 停止
+func (c *Context) Every(d time.Duration, msg any) *Timer {
+	return c.proc.schedule(d, true, msg)
+}
+
+// Timer actor 定时器
+type Timer struct {
+	proc   *process
+	timer  *time.Timer
+	repeat bool
+}
+
+// Stop 停止定时器
+// 在 actor goroutine 中调用时保证之后不会再收到该定时器的消息
+func (t *Timer) Stop() {
+	t.timer.Stop()
+	t.proc.mu.Lock()
+	delete(t.proc.timers, t)
+	t.proc.mu.Unlock()
+}
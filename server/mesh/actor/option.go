@@ -0,0 +1,40 @@
+package actor
+
+import "time"
+
+const defaultMailboxSize = 1024
+
+// options 选项
+type options struct {
+	mailboxSize int           // 邮箱容量
+	idleTimeout time.Duration // 空闲回收时间, 0 表示不回收
+}
+
+// Option 定义 System 可选配置函数
+type Option func(*options)
+
+func defaultOptions() *options {
+	return &options{
+		mailboxSize: defaultMailboxSize,
+	}
+}
+
+// MailboxSize 设置每个 actor 的邮箱容量, 默认 1024
+// 邮箱已满时投递返回 ErrMailboxFull
+func MailboxSize(size int) Option {
+	return func(o *options) {
+		if size > 0 {
+			o.mailboxSize = size
+		}
+	}
+}
+
+// IdleTimeout 设置空闲回收时间, 默认 0(不回收)
+// actor 超过该时间未收到消息时自动停止并从 System 中移除, 下次投递时重新创建
+func IdleTimeout(d time.Duration) Option {
+	return func(o *options) {
+		if d > 0 {
+			o.idleTimeout = d
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package actor
+
+import (
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/byteweap/meta/component/log"
+)
+
+// message 邮箱消息
+type message struct {
+	msg   any
+	fn    Func
+	timer *Timer // 定时器消息, 定时器已停止时丢弃
+}
+
+// process actor 运行实体: 邮箱 + goroutine
+type process struct {
+	id    int64
+	sys   *System
+	actor Actor
+
+	mailbox  chan message
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+
+	mu      sync.RWMutex
+	stopped bool // 已停止接收消息
+	timers  map[*Timer]struct{}
+
+	stopping bool // 由 actor 自身请求停止, 仅在 actor goroutine 中访问
+}
+
+func newProcess(sys *System, id int64, a Actor) *process {
+	return &process{
+		id:      id,
+		sys:     sys,
+		actor:   a,
+		mailbox: make(chan message, sys.opts.mailboxSize),
+		stopCh:  make(chan struct{}),
+		done:    make(chan struct{}),
+		timers:  make(map[*Timer]struct{}),
+	}
+}
+
+func (p *process) ref() *Ref {
+	return &Ref{id: p.id, proc: p}
+}
+
+// send 投递消息, 不阻塞
+func (p *process) send(m message) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.stopped {
+		return ErrStopped
+	}
+	select {
+	case p.mailbox <- m:
+		return nil
+	default:
+		return ErrMailboxFull
+	}
+}
+
+// stop 通知 actor 停止
+func (p *process) stop() {
+	p.stopOnce.Do(func() { close(p.stopCh) })
+}
+
+// schedule 创建定时器, 到期后向邮箱投递消息
+func (p *process) schedule(d time.Duration, repeat bool, msg any) *Timer {
+	t := &Timer{proc: p, repeat: repeat}
+	p.mu.Lock()
+	p.timers[t] = struct{}{}
+	p.mu.Unlock()
+
+	fire := func() {
+		if err := p.send(message{msg: msg, timer: t}); err != nil && err != ErrStopped {
+			log.Warnf("actor %d timer deliver error: %v", p.id, err)
+		}
+		if !repeat {
+			return
+		}
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if _, ok := p.timers[t]; ok && !p.stopped {
+			t.timer.Reset(d)
+		}
+	}
+	p.mu.Lock()
+	t.timer = time.AfterFunc(d, fire)
+	p.mu.Unlock()
+	return t
+}
+
+// run actor 主循环
+func (p *process) run() {
+	defer close(p.done)
+	defer p.sys.remove(p)
+
+	ctx := &Context{proc: p}
+	if s, ok := p.actor.(Starter); ok {
+		p.invoke(func() { s.OnStart(ctx) })
+	}
+
+	var (
+		idle  <-chan time.Time
+		timer *time.Timer
+	)
+	if d := p.sys.opts.idleTimeout; d > 0 {
+		timer = time.NewTimer(d)
+		defer timer.Stop()
+		idle = timer.C
+	}
+
+	for !p.stopping {
+		select {
+		case m := <-p.mailbox:
+			p.handle(ctx, m)
+			if timer != nil {
+				timer.Reset(p.sys.opts.idleTimeout)
+			}
+		case <-idle:
+			if p.passivate() {
+				p.shutdown(ctx)
+				return
+			}
+			timer.Reset(p.sys.opts.idleTimeout)
+		case <-p.stopCh:
+			p.stopping = true
+		}
+	}
+	p.shutdown(ctx)
+}
+
+// passivate 邮箱为空时停止接收消息, 返回是否回收
+func (p *process) passivate() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.mailbox) > 0 {
+		return false
+	}
+	p.stopped = true
+	return true
+}
+
+// shutdown 停止接收消息, 处理剩余消息并调用停止回调
+func (p *process) shutdown(ctx *Context) {
+	p.mu.Lock()
+	p.stopped = true
+	for t := range p.timers {
+		t.timer.Stop()
+	}
+	clear(p.timers)
+	p.mu.Unlock()
+
+	// 已停止接收, 邮箱中不会再有新消息
+	for len(p.mailbox) > 0 {
+		p.handle(ctx, <-p.mailbox)
+	}
+	if s, ok := p.actor.(Stopper); ok {
+		p.invoke(func() { s.OnStop(ctx) })
+	}
+}
+
+// handle 处理一条消息
+func (p *process) handle(ctx *Context, m message) {
+	if m.timer != nil {
+		if !p.timerValid(m.timer) {
+			return
+		}
+		if !m.timer.repeat {
+			p.mu.Lock()
+			delete(p.timers, m.timer)
+			p.mu.Unlock()
+		}
+	}
+	if m.fn != nil {
+		p.invoke(func() { m.fn(ctx) })
+		return
+	}
+	p.invoke(func() { p.actor.Receive(ctx, m.msg) })
+}
+
+// timerValid 定时器消息是否有效(定时器未被停止)
+func (p *process) timerValid(t *Timer) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	_, ok := p.timers[t]
+	return ok
+}
+
+// invoke 执行回调并捕获异常, 单条消息异常不影响 actor 继续运行
+func (p *process) invoke(fn func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			p.sys.panics.Add(1)
+			log.Errorf("actor %d handle message panic, error: %v\n%s", p.id, r, debug.Stack())
+		}
+	}()
+	fn()
+}
@@ -0,0 +1,39 @@
+package actor
+
+import (
+	"github.com/byteweap/meta/component/log"
+	es "github.com/byteweap/meta/errors"
+	"github.com/byteweap/meta/server/mesh"
+)
+
+// Handler actor 内的 mesh 路由处理函数
+type Handler[T any] func(a *Context, ctx *mesh.Context, req *T)
+
+// KeyFunc 从 mesh 消息中提取目标 actor id, 如玩家 uid、房间 id
+type KeyFunc[T any] func(ctx *mesh.Context, req *T) int64
+
+// ByUid 以玩家 uid 作为 actor id
+func ByUid[T any](ctx *mesh.Context, _ *T) int64 {
+	return ctx.Uid()
+}
+
+// Route 将 mesh 路由消息转发到 actor 中处理
+// 返回值可直接用于 mesh.Wrap, 例如:
+//
+//	m.Route(1, 1, mesh.Wrap(actor.Route(rooms, roomID, h.EnterRoom)))
+//
+// 消息投递失败(邮箱已满、System 已关闭)时向客户端返回 503
+func Route[T any](sys *System, key KeyFunc[T], handler Handler[T]) func(*mesh.Context, *T) {
+	return func(ctx *mesh.Context, req *T) {
+		id := key(ctx, req)
+		// mesh.Context 会被回收复用, 投递到其它 goroutine 前须复制
+		c := ctx.Copy()
+		err := sys.Exec(id, func(a *Context) {
+			handler(a, c, req)
+		})
+		if err != nil {
+			log.Errorf("actor route deliver error, id: %v, cmd: %v, err: %v", id, ctx.Cmd(), err)
+			ctx.Error(es.ServiceUnavailable(mesh.ReasonUnavailable, "server busy").WithCause(err))
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package actor
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// System actor 容器, 按 id 管理 actor 的创建、投递与停止
+type System struct {
+	opts     *options
+	producer Producer
+
+	mu     sync.RWMutex
+	procs  map[int64]*process
+	closed bool
+	wg     sync.WaitGroup
+
+	panics atomic.Uint64 // actor 消息处理异常次数
+}
+
+// NewSystem 创建 actor 容器, producer 用于按 id 创建 actor
+func NewSystem(producer Producer, opts ...Option) *System {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+	return &System{
+		opts:     o,
+		producer: producer,
+		procs:    make(map[int64]*process),
+	}
+}
+
+// Spawn 获取 id 对应的 actor, 不存在时创建并启动
+func (s *System) Spawn(id int64) (*Ref, error) {
+	p, err := s.spawn(id)
+	if err != nil {
+		return nil, err
+	}
+	return p.ref(), nil
+}
+
+func (s *System) spawn(id int64) (*process, error) {
+	s.mu.RLock()
+	p, ok := s.procs[id]
+	s.mu.RUnlock()
+	if ok {
+		return p, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, ErrStopped
+	}
+	if p, ok = s.procs[id]; ok {
+		return p, nil
+	}
+	if s.producer == nil {
+		return nil, ErrNoProducer
+	}
+	p = newProcess(s, id, s.producer(id))
+	s.procs[id] = p
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		p.run()
+	}()
+	return p, nil
+}
+
+// Get 获取正在运行的 actor
+func (s *System) Get(id int64) (*Ref, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p, ok := s.procs[id]
+	if !ok {
+		return nil, false
+	}
+	return p.ref(), true
+}
+
+// Tell 向 id 对应的 actor 投递消息, actor 不存在时自动创建
+func (s *System) Tell(id int64, msg any) error {
+	return s.deliver(id, message{msg: msg})
+}
+
+// Exec 在 id 对应的 actor goroutine 中执行 fn, actor 不存在时自动创建
+func (s *System) Exec(id int64, fn Func) error {
+	return s.deliver(id, message{fn: fn})
+}
+
+// deliver 投递消息, actor 恰好被回收时重新创建后再次投递
+func (s *System) deliver(id int64, m message) error {
+	for {
+		p, err := s.spawn(id)
+		if err != nil {
+			return err
+		}
+		if err = p.send(m); err != ErrStopped {
+			return err
+		}
+		<-p.done
+	}
+}
+
+// Stop 停止 id 对应的 actor, 剩余消息处理完毕后返回
+// 不可在 actor 自身的 goroutine 中调用, 应使用 Context.Stop
+func (s *System) Stop(id int64) {
+	s.mu.RLock()
+	p, ok := s.procs[id]
+	s.mu.RUnlock()
+	if !ok {
+		return
+	}
+	p.stop()
+	<-p.done
+}
+
+// Len 返回正在运行的 actor 数量
+func (s *System) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.procs)
+}
+
+// Panics 返回 actor 消息处理异常次数
+func (s *System) Panics() uint64 {
+	return s.panics.Load()
+}
+
+// Shutdown 停止所有 actor 并等待退出, 之后不再接受新的 actor
+func (s *System) Shutdown() {
+	s.mu.Lock()
+	s.closed = true
+	for _, p := range s.procs {
+		p.stop()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+// remove actor 退出时从容器中移除
+func (s *System) remove(p *process) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.procs[p.id] == p {
+		delete(s.procs, p.id)
+	}
+}
+
+// Ref actor 引用
+type Ref struct {
+	id   int64
+	proc *process
+}
+
+// ID 返回 actor id
+func (r *Ref) ID() int64 {
+	return r.id
+}
+
+// Tell 投递消息, actor 已停止时返回 ErrStopped, 邮箱已满时返回 ErrMailboxFull
+func (r *Ref) Tell(msg any) error {
+	return r.proc.send(message{msg: msg})
+}
+
+// Exec 在 actor goroutine 中执行 fn
+func (r *Ref) Exec(fn Func) error {
+	return r.proc.send(message{fn: fn})
+}
+
+// Stop 停止 actor, 剩余消息处理完毕后返回
+// 不可在 actor 自身的 goroutine 中调用, 应使用 Context.Stop
+func (r *Ref) Stop() {
+	r.proc.stop()
+	<-r.proc.done
+}
@@ -0,0 +1,105 @@
+package mesh
+
+import (
+	"strings"
+	"sync/atomic"
+
+	"github.com/byteweap/meta/component/log"
+	es "github.com/byteweap/meta/errors"
+	"github.com/byteweap/meta/internal/cluster"
+)
+
+// BindTo 绑定玩家到本服务的指定节点
+// 绑定到其它节点时, 本节点不再视该玩家为已绑定
+func (m *Mesh) BindTo(uid int64, node string) error {
+	if m.opts.locator == nil {
+		return es.ErrLocatorRequired
+	}
+	m.cancelUnbind(uid)
+	if err := m.opts.locator.Bind(m.ctx, uid, m.appName, node); err != nil {
+		return err
+	}
+	if node == m.appID {
+		m.markBound(uid)
+	} else {
+		m.markUnbound(uid)
+	}
+	return nil
+}
+
+// Unbind 解除玩家与本节点的绑定
+func (m *Mesh) Unbind(uid int64) error {
+	if m.opts.locator == nil {
+		return es.ErrLocatorRequired
+	}
+	m.cancelUnbind(uid)
+	if err := m.opts.locator.UnBind(m.ctx, uid, m.appName, m.appID); err != nil {
+		return err
+	}
+	m.markUnbound(uid)
+	return nil
+}
+
+// markBound 记录玩家已绑定到本节点, 首次绑定时触发 EventBind
+func (m *Mesh) markBound(uid int64) {
+	if _, loaded := m.bound.LoadOrStore(uid, struct{}{}); !loaded {
+		m.fireLocal(uid, EventBind)
+	}
+}
+
+// markUnbound 移除玩家的本节点绑定记录, 原已绑定时触发 EventUnbind
+func (m *Mesh) markUnbound(uid int64) {
+	if _, loaded := m.bound.LoadAndDelete(uid); loaded {
+		m.fireLocal(uid, EventUnbind)
+	}
+}
+
+// autoBind 首条路由消息时自动绑定玩家到本节点
+// 经服务级主题(无状态)投递的消息不绑定
+func (m *Mesh) autoBind(uid int64, subject string) {
+	if !m.opts.autoBind || uid <= 0 || strings.HasSuffix(subject, "."+cluster.AnyNode) {
+		return
+	}
+	if _, ok := m.bound.Load(uid); ok {
+		return
+	}
+	if err := m.BindTo(uid, m.appID); err != nil {
+		log.Errorf("mesh auto bind error, uid: %v, err: %v", uid, err)
+	}
+}
+
+// scheduleUnbind 玩家掉线, 宽限期后解绑
+func (m *Mesh) scheduleUnbind(uid int64) {
+	if !m.opts.autoUnbind || uid <= 0 || m.opts.locator == nil {
+		return
+	}
+	var timer atomic.Pointer[Timer]
+	unbind := func() {
+		// 仅处理最近一次掉线调度的解绑, 已被取消或替换时忽略
+		if t := timer.Load(); t != nil && !m.unbinds.CompareAndDelete(uid, t) {
+			return
+		}
+		if err := m.opts.locator.UnBind(m.ctx, uid, m.appName, m.appID); err != nil {
+			log.Errorf("mesh auto unbind error, uid: %v, err: %v", uid, err)
+			return
+		}
+		m.markUnbound(uid)
+	}
+	if m.opts.unbindGrace <= 0 {
+		m.cancelUnbind(uid)
+		unbind()
+		return
+	}
+	t := m.AfterFunc(m.opts.unbindGrace, unbind, TimerKey(uint64(uid)))
+	timer.Store(t)
+	if prev, ok := m.unbinds.Swap(uid, t); ok {
+		prev.(*Timer).Stop()
+	}
+}
+
+// cancelUnbind 取消待执行的掉线解绑
+func (m *Mesh) cancelUnbind(uid int64) {
+	if prev, ok := m.unbinds.LoadAndDelete(uid); ok {
+		prev.(*Timer).Stop()
+	}
+}
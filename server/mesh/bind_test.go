@@ -0,0 +1,109 @@
+package mesh
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/byteweap/meta/component/broker"
+	"github.com/byteweap/meta/envelope"
+	"github.com/byteweap/meta/internal/cluster"
+)
+
+type mockLocator struct {
+	mu      sync.Mutex
+	binds   int
+	unbinds int
+	nodes   map[int64]string
+}
+
+func (l *mockLocator) ID() string { return "mock" }
+
+func (l *mockLocator) AllNodes(context.Context, int64) (map[string]string, error) { return nil, nil }
+
+func (l *mockLocator) Node(_ context.Context, uid int64, _ string) (string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.nodes[uid], nil
+}
+
+func (l *mockLocator) Bind(_ context.Context, uid int64, _, node string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.nodes == nil {
+		l.nodes = make(map[int64]string)
+	}
+	l.binds++
+	l.nodes[uid] = node
+	return nil
+}
+
+func (l *mockLocator) UnBind(_ context.Context, uid int64, _, node string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.unbinds++
+	if l.nodes[uid] == node {
+		delete(l.nodes, uid)
+	}
+	return nil
+}
+
+func (l *mockLocator) Close() error { return nil }
+
+func (l *mockLocator) node(uid int64) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.nodes[uid]
+}
+
+// TestAutoBind 验证首条路由消息自动绑定, 后续消息不重复绑定
+func TestAutoBind(t *testing.T) {
+	loc := &mockLocator{}
+	m := New(Broker(&mockBroker{}), Locator(loc), AutoBind(true))
+	m.ctx, m.appName, m.appID = context.Background(), "game", "game-1"
+	m.RouteX(5001, 1, func(_ *Context, _ *envelope.Header) {})
+
+	header := cluster.BuildHeader(1001, cluster.Event_Business, "gate.reply", "gate", "game")
+	for range 2 {
+		raw := mustBusinessMessage(t, 5001, 1, "game", &envelope.Header{})
+		m.handleTask(task{msg: &broker.Message{Header: header, Data: raw}})
+	}
+	if loc.node(1001) != "game-1" || loc.binds != 1 {
+		t.Fatalf("unexpected binding: node=%q binds=%d", loc.node(1001), loc.binds)
+	}
+}
+
+// TestAutoUnbindGrace 验证掉线宽限期内重连取消解绑, 宽限期后解绑
+func TestAutoUnbindGrace(t *testing.T) {
+	loc := &mockLocator{}
+	m := New(Broker(&mockBroker{}), Locator(loc), AutoUnbind(30*time.Millisecond), TimerTick(time.Millisecond))
+	startExecutor(t, m)
+	m.appID = "game-1"
+	go m.wheel.Run(m.ctx)
+
+	if err := m.BindTo(1001, m.appID); err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	event := func(e cluster.Event) {
+		m.post(1001, func() {
+			m.handlerMessage(&broker.Message{Header: cluster.BuildHeader(1001, e, "", "gate", "game")})
+		})
+	}
+
+	event(cluster.Event_Offline)
+	event(cluster.Event_Reconnect)
+	time.Sleep(60 * time.Millisecond)
+	if loc.node(1001) != "game-1" {
+		t.Fatal("reconnect within grace period should keep binding")
+	}
+
+	event(cluster.Event_Offline)
+	deadline := time.Now().Add(time.Second)
+	for loc.node(1001) != "" {
+		if time.Now().After(deadline) {
+			t.Fatal("binding not released after grace period")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
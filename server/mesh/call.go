@@ -0,0 +1,164 @@
+package mesh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/byteweap/meta/component/broker"
+	es "github.com/byteweap/meta/errors"
+	"github.com/byteweap/meta/internal/cluster"
+	"github.com/byteweap/meta/metadata"
+)
+
+// CallOption Call 可选配置
+type CallOption func(*callOptions)
+
+type callOptions struct {
+	uid     int64
+	node    string
+	anyNode bool
+	timeout time.Duration
+}
+
+// CallUid 按玩家定位目标节点
+// 优先调用玩家当前绑定在目标服务上的节点, 未绑定时通过服务发现选择;
+// 同时携带 uid, 目标服务按 uid 与该玩家的其它消息串行处理
+func CallUid(uid int64) CallOption {
+	return func(o *callOptions) {
+		o.uid = uid
+	}
+}
+
+// CallNode 指定目标节点 ID
+func CallNode(node string) CallOption {
+	return func(o *callOptions) {
+		o.node = node
+	}
+}
+
+// CallAnyNode 发送到目标服务的服务级主题, 由 broker 负载均衡到任一节点
+// 目标服务须以 SubscribeService 模式订阅, 适用于无状态服务, 无需玩家绑定与服务发现
+func CallAnyNode() CallOption {
+	return func(o *callOptions) {
+		o.anyNode = true
+	}
+}
+
+// CallTimeout 设置本次调用超时时间, 默认使用 mesh.RequestTimeout 配置
+func CallTimeout(d time.Duration) CallOption {
+	return func(o *callOptions) {
+		o.timeout = d
+	}
+}
+
+// Call 调用其它服务的 request-reply 路由
+// ctx 的截止时间与元数据(metadata.FromContext)随请求透传给目标服务
+// 请求使用 mesh.Codec 配置的编解码器序列化, 响应反序列化为 Resp;
+// 失败时返回 *errors.Error: 目标服务返回的业务错误原样返回;
+// 无可用节点返回 503(ReasonUnavailable), 超时返回 504(ReasonTimeout)
+//
+// 示例:
+//
+//	resp, err := mesh.Call[pb.FindRoomRequest, pb.FindRoomResponse](ctx, m, "room", "findRoom", "v1", req)
+func Call[Req, Resp any](ctx context.Context, m *Mesh, service, cmd, version string, req *Req, opts ...CallOption) (*Resp, error) {
+	var in any
+	if req != nil {
+		in = req
+	}
+	data, err := m.call(ctx, service, cmd, version, in, opts)
+	if err != nil {
+		return nil, err
+	}
+	resp := new(Resp)
+	if len(data) > 0 {
+		if err = m.opts.codec.Unmarshal(data, resp); err != nil {
+			return nil, fmt.Errorf("mesh call %s %s.%s unmarshal response error: %w", service, cmd, version, err)
+		}
+	}
+	return resp, nil
+}
+
+// call 序列化请求、选择节点并发送 request-reply 消息
+func (m *Mesh) call(ctx context.Context, service, cmd, version string, req any, opts []CallOption) ([]byte, error) {
+	o := &callOptions{timeout: m.opts.callTimeout}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	var (
+		data []byte
+		err  error
+	)
+	if req != nil {
+		if data, err = m.opts.codec.Marshal(req); err != nil {
+			return nil, fmt.Errorf("mesh call %s %s.%s marshal request error: %w", service, cmd, version, err)
+		}
+	}
+	node, err := m.resolve(ctx, service, o)
+	if err != nil {
+		return nil, es.ServiceUnavailable(ReasonUnavailable, fmt.Sprintf("service %s unavailable", service)).WithCause(err)
+	}
+
+	header := cluster.BuildHeader(o.uid, cluster.Event_Business, "", m.appName, service)
+	header.Set("cmd", cmd)
+	header.Set("version", version)
+
+	if o.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.timeout)
+		defer cancel()
+	}
+	propagate(ctx, header)
+	subject := cluster.Subject(m.opts.prefix, m.appName, service, node)
+	result, err := m.opts.broker.Request(ctx, subject, data, broker.RequestHeader(header))
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil, es.GatewayTimeout(ReasonTimeout, fmt.Sprintf("call %s %s.%s timeout", service, cmd, version)).WithCause(err)
+		}
+		return nil, err
+	}
+	if e := cluster.GetErrorBy(result.Header); e != nil {
+		return nil, e
+	}
+	return result.Data, nil
+}
+
+// propagate 将 ctx 的截止时间与元数据写入请求消息头, ctx 为 Context/RpcContext 时自动继承上游的值
+func propagate(ctx context.Context, header broker.Header) {
+	if deadline, ok := ctx.Deadline(); ok {
+		cluster.SetDeadline(header, deadline)
+	}
+	if md, ok := metadata.FromContext(ctx); ok {
+		cluster.SetMetadata(header, md)
+	}
+}
+
+// resolve 选择目标节点: 指定节点 > 服务级主题 > 玩家绑定节点 > 服务发现
+func (m *Mesh) resolve(ctx context.Context, service string, o *callOptions) (string, error) {
+	if o.node != "" {
+		return o.node, nil
+	}
+	if o.anyNode {
+		return cluster.AnyNode, nil
+	}
+	if o.uid > 0 && m.opts.locator != nil {
+		node, err := m.opts.locator.Node(ctx, o.uid, service)
+		if err != nil {
+			return "", err
+		}
+		if node != "" {
+			return node, nil
+		}
+	}
+	sel, err := m.ensure(service)
+	if err != nil {
+		return "", err
+	}
+	node, err := sel.Select("")
+	if err != nil {
+		return "", err
+	}
+	return node.ID(), nil
+}
@@ -0,0 +1,177 @@
+package mesh
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/byteweap/meta/component/broker"
+	"github.com/byteweap/meta/component/registry"
+	"github.com/byteweap/meta/component/selector"
+	"github.com/byteweap/meta/encoding/proto"
+	"github.com/byteweap/meta/envelope"
+	es "github.com/byteweap/meta/errors"
+	"github.com/byteweap/meta/internal/cluster"
+)
+
+type mockWatcher struct {
+	once      sync.Once
+	instances []*registry.ServiceInstance
+	stop      chan struct{}
+}
+
+func (w *mockWatcher) Next() ([]*registry.ServiceInstance, error) {
+	first := false
+	w.once.Do(func() { first = true })
+	if first {
+		return w.instances, nil
+	}
+	<-w.stop
+	return nil, errors.New("watcher stopped")
+}
+
+func (w *mockWatcher) Stop() error {
+	close(w.stop)
+	return nil
+}
+
+type mockRegistry struct {
+	watches int
+	watcher *mockWatcher
+}
+
+func (r *mockRegistry) ID() string { return "mock" }
+
+func (r *mockRegistry) Register(context.Context, *registry.ServiceInstance) error { return nil }
+
+func (r *mockRegistry) Deregister(context.Context, *registry.ServiceInstance) error { return nil }
+
+func (r *mockRegistry) GetService(context.Context, string) ([]*registry.ServiceInstance, error) {
+	return r.watcher.instances, nil
+}
+
+func (r *mockRegistry) Watch(context.Context, string) (registry.Watcher, error) {
+	r.watches++
+	return r.watcher, nil
+}
+
+type mockSelector struct {
+	mu    sync.Mutex
+	nodes []selector.Node
+}
+
+func (s *mockSelector) Select(string, ...selector.Filter) (selector.Node, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.nodes) == 0 {
+		return nil, selector.ErrNoAvailableNode
+	}
+	return s.nodes[0], nil
+}
+
+func (s *mockSelector) Update(nodes []selector.Node) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nodes = nodes
+}
+
+func (s *mockSelector) Nodes() []selector.Node {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.nodes
+}
+
+// TestCallDiscovery 验证通过服务发现选择节点并解码响应
+func TestCallDiscovery(t *testing.T) {
+	reg := &mockRegistry{watcher: &mockWatcher{
+		instances: []*registry.ServiceInstance{{ID: "room-1", Name: "room"}},
+		stop:      make(chan struct{}),
+	}}
+	data, err := proto.Marshal(&envelope.Header{Seq: 9})
+	if err != nil {
+		t.Fatalf("marshal response: %v", err)
+	}
+	mb := &mockBroker{reqResp: &broker.Message{
+		Data:   data,
+		Header: broker.Header{"code": []string{"200"}},
+	}}
+	m := New(Broker(mb), Discovery(reg), SelectorFunc(func() selector.Selector { return &mockSelector{} }))
+	m.ctx, m.appName = context.Background(), "game"
+	defer m.stopWatchers()
+
+	var resp *envelope.Header
+	deadline := time.Now().Add(time.Second)
+	for {
+		resp, err = Call[envelope.Header, envelope.Header](context.Background(), m, "room", "findRoom", "v1", &envelope.Header{Cmd: 1})
+		if !errors.Is(err, selector.ErrNoAvailableNode) || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("call: %v", err)
+	}
+	if resp.GetSeq() != 9 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if mb.reqSubject != cluster.Subject("meta", "game", "room", "room-1") {
+		t.Fatalf("unexpected subject: %s", mb.reqSubject)
+	}
+	if mb.reqHeader.Get("cmd") != "findRoom" || mb.reqHeader.Get("version") != "v1" {
+		t.Fatalf("unexpected header: %+v", mb.reqHeader)
+	}
+	if reg.watches != 1 {
+		t.Fatalf("expected single watch, got %d", reg.watches)
+	}
+}
+
+// TestCallNodeError 验证指定节点与非 200 状态码的结构化错误
+func TestCallNodeError(t *testing.T) {
+	mb := &mockBroker{reqResp: &broker.Message{
+		Header: broker.Header{"code": []string{"404"}, "tip": []string{"room not found"}, "reason": []string{"ROOM_NOT_FOUND"}},
+	}}
+	m := New(Broker(mb))
+	m.ctx, m.appName = context.Background(), "game"
+
+	_, err := Call[envelope.Header, envelope.Header](context.Background(), m, "room", "findRoom", "v1", nil,
+		CallNode("room-2"), CallUid(1001))
+	if !es.Is(err, es.NotFound("ROOM_NOT_FOUND", "")) || es.FromError(err).Message != "room not found" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if mb.reqSubject != cluster.Subject("meta", "game", "room", "room-2") {
+		t.Fatalf("unexpected subject: %s", mb.reqSubject)
+	}
+	if cluster.GetUidBy(mb.reqHeader) != 1001 {
+		t.Fatalf("uid header not set: %+v", mb.reqHeader)
+	}
+}
+
+// TestSubscribeService 验证服务级主题的 Queue Group 订阅与 CallAnyNode
+func TestSubscribeService(t *testing.T) {
+	mb := &mockBroker{}
+	m := New(Broker(mb), Subscription(SubscribeNode|SubscribeService))
+	m.ctx, m.appName, m.appID = context.Background(), "mail", "mail-1"
+
+	subs, err := m.subscribe(func(*broker.Message) {})
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	if len(subs) != 2 {
+		t.Fatalf("expected 2 subscriptions, got %d", len(subs))
+	}
+	if mb.subSubjects[1] != cluster.ServiceSubject("meta", "*", "mail") || mb.subQueues[1] != "mail" {
+		t.Fatalf("unexpected service subscription: %v %v", mb.subSubjects, mb.subQueues)
+	}
+	if mb.subQueues[0] != "" {
+		t.Fatalf("node subscription should not use queue group: %v", mb.subQueues)
+	}
+
+	if _, err = Call[envelope.Header, envelope.Header](context.Background(), m, "shop", "buy", "v1", nil, CallAnyNode()); err != nil {
+		t.Fatalf("call: %v", err)
+	}
+	if mb.reqSubject != cluster.ServiceSubject("meta", "mail", "shop") {
+		t.Fatalf("unexpected subject: %s", mb.reqSubject)
+	}
+}
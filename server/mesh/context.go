@@ -9,11 +9,12 @@ import (
 	"github.com/byteweap/meta/component/broker"
 	"github.com/byteweap/meta/component/log"
 	"github.com/byteweap/meta/envelope"
+	es "github.com/byteweap/meta/errors"
 	"github.com/byteweap/meta/internal/cluster"
-	"github.com/byteweap/meta/pkg/lang"
 )
 
 // Context 网关消息上下文
+// 实现 context.Context, 可直接传递给 Call 等下游调用以透传截止时间与元数据
 type Context struct {
 
 	// broker message
@@ -25,13 +26,18 @@ type Context struct {
 	// universal message
 	seq         uint64
 	fromService string
+	fromNode    string
 	toApp       string
 	cmd         uint32
 	version     uint32
 	timestamp   int64
 
+	replied bool // 是否已回复
+
 	// mesh
 	mesh *Mesh
+
+	stdContext
 }
 
 var ctxPool = sync.Pool{
@@ -58,10 +64,13 @@ func (c *Context) release() {
 
 	c.seq = 0
 	c.fromService = ""
+	c.fromNode = ""
 	c.toApp = ""
 	c.cmd = 0
 	c.version = 0
+	c.replied = false
 	c.mesh = nil
+	c.releaseStd()
 	ctxPool.Put(c)
 }
 
@@ -74,9 +83,11 @@ func (c *Context) reset(mesh *Mesh, msg *broker.Message, e *envelope.IMessage) {
 	c.subject = msg.Subject
 	c.reply = cluster.GetReplyBy(msg.Header)
 	c.fromService = cluster.GetFromServiceBy(msg.Header)
+	c.fromNode = cluster.GetFromNodeBy(msg.Header)
 	c.toApp = cluster.GetToServiceBy(msg.Header)
 	c.event = cluster.GetEventBy(msg.Header)
 	c.uid = cluster.GetUidBy(msg.Header)
+	c.resetStd(mesh.ctx, msg.Header)
 
 	if e == nil || e.GetHeader() == nil {
 		c.seq = 0
@@ -107,6 +118,11 @@ func (c *Context) FromService() string {
 	return c.fromService
 }
 
+// FromNode 返回来源节点 ID, 来自 gate 的消息为网关节点
+func (c *Context) FromNode() string {
+	return c.fromNode
+}
+
 // ToService 返回应用标识
 func (c *Context) ToService() string {
 	return c.toApp
@@ -132,6 +148,17 @@ func (c *Context) Timestamp() int64 {
 	return c.timestamp
 }
 
+// Bind 绑定当前玩家到本节点
+// 绑定后 gate 将该玩家发往本服务的消息固定路由到本节点
+func (c *Context) Bind() error {
+	return c.mesh.BindTo(c.uid, c.mesh.appID)
+}
+
+// Unbind 解除当前玩家与本节点的绑定
+func (c *Context) Unbind() error {
+	return c.mesh.Unbind(c.uid)
+}
+
 // Copy 复制
 // 当在新的goroutine中使用context时,应使用Copy方法复制context
 func (c *Context) Copy() *Context {
@@ -145,16 +172,35 @@ func (c *Context) Copy() *Context {
 		uid:         c.uid,
 		seq:         c.seq,
 		fromService: c.fromService,
+		fromNode:    c.fromNode,
 		toApp:       c.toApp,
 		cmd:         c.cmd,
 		version:     c.version,
 		timestamp:   c.timestamp,
 		mesh:        c.mesh,
+		stdContext:  c.copyStd(),
 	}
 }
 
-// OkResp 返回成功响应
+// OkResp 返回成功响应, 仅使用第一个参数作为响应数据
 func (c *Context) OkResp(args ...proto.Message) {
+	if len(args) > 1 {
+		log.Warnf("[mesh].[OkResponse] only the first payload is sent, got: %d", len(args))
+	}
+	var payload []byte
+	if len(args) > 0 {
+		var err error
+		if payload, err = proto.Marshal(args[0]); err != nil {
+			log.Errorf("[mesh].[OkResponse] marshal payload error, err: %v", err)
+			return
+		}
+	}
+	c.okResp(payload)
+}
+
+// okResp 发送已序列化的成功响应
+func (c *Context) okResp(payload []byte) {
+	c.replied = true
 
 	out := &envelope.OMessage{
 		Header: &envelope.Header{
@@ -165,15 +211,7 @@ func (c *Context) OkResp(args ...proto.Message) {
 		},
 		Service: c.mesh.appName,
 		MsgType: envelope.MsgType_RESPONSE,
-	}
-
-	var err error
-	if len(args) > 0 {
-		out.Payload, err = proto.Marshal(args[0])
-		if err != nil {
-			log.Errorf("[mesh].[OkResponse] marshal payload error, err: %v", err)
-			return
-		}
+		Payload: payload,
 	}
 	bytes, err := proto.Marshal(out)
 	if err != nil {
@@ -193,9 +231,23 @@ func (c *Context) OkResp(args ...proto.Message) {
 
 // ErrResp 返回错误响应
 func (c *Context) ErrResp(code int, args ...string) {
+	tip := "mesh internal error"
+	if len(args) > 0 {
+		tip = args[0]
+	}
+	c.Error(es.New(code, "", tip))
+}
 
-	tip := lang.If(len(args) > 0, args[0], "mesh internal error")
+// Error 返回结构化错误响应, 状态码、提示信息、错误原因与附加信息一并下发给客户端
+// 非 *errors.Error 的错误按 500 处理
+func (c *Context) Error(err error) {
 
+	e := es.FromError(err)
+	if e == nil {
+		c.OkResp()
+		return
+	}
+	c.replied = true
 	out := &envelope.OMessage{
 		Header: &envelope.Header{
 			Seq:       c.Seq(),
@@ -205,10 +257,7 @@ func (c *Context) ErrResp(code int, args ...string) {
 		},
 		Service: c.mesh.appName,
 		MsgType: envelope.MsgType_RESPONSE,
-		Result: &envelope.Code{
-			Code: int32(code),
-			Tip:  tip,
-		},
+		Result:  toCode(e),
 	}
 	bytes, err := proto.Marshal(out)
 	if err != nil {
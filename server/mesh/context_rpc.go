@@ -1,14 +1,22 @@
 package mesh
 
 import (
+	"net/http"
 	"sync"
 
 	"github.com/byteweap/meta/component/broker"
+	es "github.com/byteweap/meta/errors"
+	"github.com/byteweap/meta/internal/cluster"
 )
 
+// RpcContext request-reply 消息上下文
+// 实现 context.Context, 截止时间为调用方写入的截止时间, 可直接传递给下游调用
 type RpcContext struct {
 	subject, reply, cmd, version string
+	msg                          *broker.Message
 	mesh                         *Mesh
+
+	stdContext
 }
 
 var reqCtxPool = sync.Pool{
@@ -30,7 +38,9 @@ func (ctx *RpcContext) reset(mesh *Mesh, msg *broker.Message) {
 	ctx.reply = msg.Reply
 	ctx.cmd = msg.Header.Get("cmd")
 	ctx.version = msg.Header.Get("version")
+	ctx.msg = msg
 	ctx.mesh = mesh
+	ctx.resetStd(mesh.ctx, msg.Header)
 }
 
 // release 清理上下文字段并归还对象池
@@ -42,7 +52,9 @@ func (ctx *RpcContext) release() {
 	ctx.reply = ""
 	ctx.cmd = ""
 	ctx.version = ""
+	ctx.msg = nil
 	ctx.mesh = nil
+	ctx.releaseStd()
 	reqCtxPool.Put(ctx)
 }
 
@@ -60,3 +72,19 @@ func (ctx *RpcContext) Cmd() string {
 func (ctx *RpcContext) Version() string {
 	return ctx.version
 }
+
+// Error 返回结构化错误, 作为 handler 的返回值使用, 错误原因与附加信息随回复下发给调用方
+// 示例: return ctx.Error(errors.NotFound("ROOM_NOT_FOUND", "room not found"))
+func (ctx *RpcContext) Error(err error) ([]byte, string, int) {
+	e := es.FromError(err)
+	if e == nil {
+		return nil, "ok", http.StatusOK
+	}
+	if ctx.msg != nil {
+		if ctx.msg.Header == nil {
+			ctx.msg.Header = broker.Header{}
+		}
+		cluster.SetError(ctx.msg.Header, e)
+	}
+	return nil, e.Message, e.Code
+}
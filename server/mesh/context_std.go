@@ -0,0 +1,124 @@
+package mesh
+
+import (
+	"context"
+	"time"
+
+	"github.com/byteweap/meta/component/broker"
+	"github.com/byteweap/meta/internal/cluster"
+	"github.com/byteweap/meta/metadata"
+)
+
+var (
+	_ context.Context = (*Context)(nil)
+	_ context.Context = (*RpcContext)(nil)
+)
+
+// stdContext 为 Context 与 RpcContext 实现 context.Context
+// 截止时间与元数据来自调用方写入的消息头, 父 context 为 mesh 运行 context,
+// 因此 mesh 停止或调用方截止时间到达时 Done 关闭
+type stdContext struct {
+	parent   context.Context
+	deadline time.Time
+	md       metadata.Metadata
+
+	std    context.Context // 首次使用 Done/Err/Value 时创建
+	cancel context.CancelFunc
+}
+
+// resetStd 按消息头重置
+func (c *stdContext) resetStd(parent context.Context, header broker.Header) {
+	c.parent = parent
+	c.deadline, _ = cluster.GetDeadlineBy(header)
+	c.md = cluster.GetMetadataBy(header)
+}
+
+// releaseStd 清理字段并释放截止时间定时器
+func (c *stdContext) releaseStd() {
+	if c.cancel != nil {
+		c.cancel()
+	}
+	*c = stdContext{}
+}
+
+// copyStd 复制截止时间与元数据, 派生的 context 在副本首次使用时重新创建
+func (c *stdContext) copyStd() stdContext {
+	return stdContext{
+		parent:   c.parent,
+		deadline: c.deadline,
+		md:       c.md,
+	}
+}
+
+func (c *stdContext) ctx() context.Context {
+	if c.std != nil {
+		return c.std
+	}
+	parent := c.parent
+	if parent == nil {
+		parent = context.Background()
+	}
+	if c.md != nil {
+		parent = metadata.NewContext(parent, c.md)
+	}
+	if c.deadline.IsZero() {
+		c.std = parent
+	} else {
+		c.std, c.cancel = context.WithDeadline(parent, c.deadline)
+	}
+	return c.std
+}
+
+// Deadline 返回调用方截止时间
+func (c *stdContext) Deadline() (time.Time, bool) {
+	if c.deadline.IsZero() {
+		return c.ctx().Deadline()
+	}
+	return c.deadline, true
+}
+
+// Done 调用方截止时间到达或 mesh 停止时关闭
+func (c *stdContext) Done() <-chan struct{} {
+	return c.ctx().Done()
+}
+
+// Err 返回 context 结束原因
+func (c *stdContext) Err() error {
+	return c.ctx().Err()
+}
+
+// Value 返回 context 中 key 对应的值, 元数据可通过 metadata.FromContext 获取
+func (c *stdContext) Value(key any) any {
+	return c.ctx().Value(key)
+}
+
+// Metadata 返回调用方透传的元数据, 不存在时返回 nil
+func (c *stdContext) Metadata() metadata.Metadata {
+	return c.md
+}
+
+// expired 调用方截止时间是否已过
+func (c *stdContext) expired() bool {
+	return !c.deadline.IsZero() && !time.Now().Before(c.deadline)
+}
+
+// detached 返回截止时间与元数据的快照, 不引用 Context 本身, Context 释放后仍可使用
+func (c *stdContext) detached() (context.Context, context.CancelFunc) {
+	cp := c.copyStd()
+	std := cp.ctx()
+	if cp.cancel == nil {
+		return std, func() {}
+	}
+	return std, cp.cancel
+}
+
+// detach 在启动协程前调用: Context/RpcContext 为池化对象, handler 返回后即被回收,
+// 因此替换为截止时间与元数据的快照; 其它 context 原样返回
+func detach(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c, ok := ctx.(interface {
+		detached() (context.Context, context.CancelFunc)
+	}); ok {
+		return c.detached()
+	}
+	return ctx, func() {}
+}
@@ -0,0 +1,127 @@
+package mesh
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/byteweap/meta/component/broker"
+	"github.com/byteweap/meta/envelope"
+	"github.com/byteweap/meta/internal/cluster"
+	"github.com/byteweap/meta/metadata"
+)
+
+// TestContextPropagation 验证上下文的截止时间与元数据来自消息头, 并透传给下游调用
+func TestContextPropagation(t *testing.T) {
+	mb := &mockBroker{reqResp: &broker.Message{Header: broker.Header{"code": []string{"200"}}}}
+	m := New(Broker(mb))
+	m.ctx, m.appName = context.Background(), "game"
+
+	deadline := time.Now().Add(time.Second).Truncate(time.Millisecond)
+	header := cluster.BuildHeader(1001, cluster.Event_Business, "gate.reply", "gate", "game")
+	cluster.SetDeadline(header, deadline)
+	cluster.SetMetadata(header, metadata.New("trace-id", "t1"))
+
+	ctx := newContext(m, &broker.Message{Header: header}, &envelope.IMessage{Header: &envelope.Header{Cmd: 1}})
+	defer ctx.release()
+
+	if d, ok := ctx.Deadline(); !ok || !d.Equal(deadline) {
+		t.Fatalf("unexpected deadline: %v %v", d, ok)
+	}
+	if md, ok := metadata.FromContext(ctx); !ok || md.Get("trace-id") != "t1" {
+		t.Fatalf("metadata not found in context")
+	}
+	if ctx.Err() != nil {
+		t.Fatalf("context should not be done")
+	}
+
+	if _, err := Call[envelope.Header, envelope.Header](ctx, m, "room", "findRoom", "v1", nil, CallNode("room-1")); err != nil {
+		t.Fatalf("call: %v", err)
+	}
+	if d, ok := cluster.GetDeadlineBy(mb.reqHeader); !ok || !d.Equal(deadline) {
+		t.Fatalf("deadline not propagated: %v", mb.reqHeader)
+	}
+	if cluster.GetMetadataBy(mb.reqHeader).Get("trace-id") != "t1" {
+		t.Fatalf("metadata not propagated: %v", mb.reqHeader)
+	}
+
+	// 副本在原上下文归还后仍可使用
+	cp := ctx.Copy()
+	if d, ok := cp.Deadline(); !ok || !d.Equal(deadline) || cp.Metadata().Get("trace-id") != "t1" {
+		t.Fatalf("copy lost deadline or metadata")
+	}
+}
+
+// TestRequestContextPropagation 验证 RequestContext 透传截止时间与元数据, 回复不回传请求的截止时间与元数据
+func TestRequestContextPropagation(t *testing.T) {
+	mb := &mockBroker{reqResp: &broker.Message{Header: broker.Header{"code": []string{"200"}}}}
+	m := New(Broker(mb))
+	m.ctx = context.Background()
+
+	deadline := time.Now().Add(time.Second).Truncate(time.Millisecond)
+	ctx, cancel := context.WithDeadline(metadata.NewContext(context.Background(), metadata.New("trace-id", "t1")), deadline)
+	defer cancel()
+	if _, _, code, err := m.RequestContext(ctx, "room", "findRoom", "v1", nil); err != nil || code != 200 {
+		t.Fatalf("request: %v %v", code, err)
+	}
+	if d, ok := cluster.GetDeadlineBy(mb.reqHeader); !ok || !d.Equal(deadline) {
+		t.Fatalf("deadline not propagated: %v", mb.reqHeader)
+	}
+	if cluster.GetMetadataBy(mb.reqHeader).Get("trace-id") != "t1" {
+		t.Fatalf("metadata not propagated: %v", mb.reqHeader)
+	}
+
+	m.RpcRouteX("ok", "v1", func(*RpcContext, *envelope.Header) ([]byte, string, int) {
+		return nil, "ok", 200
+	})
+	m.RpcRouteX("fail", "v1", func(*RpcContext, *envelope.Header) ([]byte, string, int) {
+		return nil, "busy", 503
+	})
+	for _, cmd := range []string{"ok", "fail"} {
+		header := broker.Header{"cmd": []string{cmd}, "version": []string{"v1"}}
+		cluster.SetDeadline(header, deadline)
+		cluster.SetMetadata(header, metadata.New("trace-id", "t1"))
+		m.handlerRequestReplyMessage(&broker.Message{Reply: "svc.reply", Header: header})
+		if _, ok := cluster.GetDeadlineBy(mb.replyHdr); ok || cluster.GetMetadataBy(mb.replyHdr) != nil {
+			t.Fatalf("%s reply carries request deadline or metadata: %v", cmd, mb.replyHdr)
+		}
+	}
+}
+
+// TestExpiredRequestDropped 验证调用方截止时间已过的请求不再处理
+func TestExpiredRequestDropped(t *testing.T) {
+	mb := &mockBroker{}
+	m := New(Broker(mb))
+
+	called := false
+	m.RpcRouteX("slow", "v1", func(ctx *RpcContext, _ *envelope.Header) ([]byte, string, int) {
+		called = true
+		return nil, "ok", 200
+	})
+	header := broker.Header{
+		"cmd":     []string{"slow"},
+		"version": []string{"v1"},
+	}
+	cluster.SetDeadline(header, time.Now().Add(-time.Second))
+	m.handlerRequestReplyMessage(&broker.Message{Reply: "svc.reply", Header: header})
+	if called || mb.replyCalls != 0 {
+		t.Fatalf("expired request should be dropped")
+	}
+
+	// 处理中可感知截止时间
+	var err error
+	m.RpcRouteX("fast", "v1", func(ctx *RpcContext, _ *envelope.Header) ([]byte, string, int) {
+		<-ctx.Done()
+		err = ctx.Err()
+		return nil, "ok", 200
+	})
+	header = broker.Header{
+		"cmd":     []string{"fast"},
+		"version": []string{"v1"},
+	}
+	cluster.SetDeadline(header, time.Now().Add(20*time.Millisecond))
+	m.handlerRequestReplyMessage(&broker.Message{Reply: "svc.reply", Header: header})
+	if err != context.DeadlineExceeded {
+		t.Fatalf("unexpected context error: %v", err)
+	}
+}
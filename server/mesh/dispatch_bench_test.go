@@ -0,0 +1,34 @@
+package mesh
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// benchmarkDispatch 模拟 handler 耗时 100µs, 对比单 worker 与 worker 池的吞吐
+func benchmarkDispatch(b *testing.B, workers int) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	exec := newExecutor(workers, defaultMessageBufferSize)
+	var wg sync.WaitGroup
+	for i := range exec.workers() {
+		go exec.run(ctx, nil, i, func(t task) { t.fn() })
+	}
+
+	b.ResetTimer()
+	wg.Add(b.N)
+	for i := range b.N {
+		exec.submit(ctx, uint64(i%1024)+1, task{fn: func() {
+			time.Sleep(100 * time.Microsecond)
+			wg.Done()
+		}})
+	}
+	wg.Wait()
+}
+
+func BenchmarkDispatchSerial(b *testing.B)    { benchmarkDispatch(b, 1) }
+func BenchmarkDispatchWorkers8(b *testing.B)  { benchmarkDispatch(b, 8) }
+func BenchmarkDispatchWorkers64(b *testing.B) { benchmarkDispatch(b, 64) }
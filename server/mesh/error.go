@@ -0,0 +1,56 @@
+package mesh
+
+import (
+	"fmt"
+
+	"github.com/byteweap/meta/component/broker"
+	"github.com/byteweap/meta/component/log"
+	"github.com/byteweap/meta/envelope"
+	es "github.com/byteweap/meta/errors"
+)
+
+// mesh 框架返回的错误原因
+const (
+	ReasonRouteNotFound = "ROUTE_NOT_FOUND" // 路由不存在
+	ReasonPanic         = "PANIC"           // handler 异常
+	ReasonUnavailable   = "UNAVAILABLE"     // 没有可用节点或投递失败
+	ReasonTimeout       = "TIMEOUT"         // 调用超时
+	ReasonMigrate       = "MIGRATE_FAILED"  // 玩家迁移失败
+	ReasonServerBusy    = "SERVER_BUSY"     // 节点过载或路由并发数已满
+	ReasonRateLimited   = "RATE_LIMITED"    // 玩家请求频率超限
+
+	ReasonUnsupportedVersion = "UNSUPPORTED_VERSION" // 路由版本不支持
+	ReasonDecodeFailed       = "DECODE_FAILED"       // payload 解码失败
+	ReasonEncodeFailed       = "ENCODE_FAILED"       // 响应序列化失败
+)
+
+// toCode 将结构化错误转换为下发给客户端的 envelope.Code
+func toCode(e *es.Error) *envelope.Code {
+	return &envelope.Code{
+		Code:     int32(e.Code),
+		Tip:      e.Message,
+		Reason:   e.Reason,
+		Metadata: e.Metadata,
+	}
+}
+
+// notFound 路由不存在
+func (m *Mesh) notFound(msg *broker.Message, e *envelope.IMessage) {
+	ctx := newContext(m, msg, e)
+	defer ctx.release()
+	if m.notFoundHandler != nil {
+		m.notFoundHandler(ctx)
+		return
+	}
+	ctx.Error(es.NotFound(ReasonRouteNotFound, fmt.Sprintf("cmd:%d version:%d not found", ctx.Cmd(), ctx.Version())))
+}
+
+// decodeError payload 解码失败
+func (m *Mesh) decodeError(ctx *Context, err error) {
+	log.Errorf("mesh pub-sub unmarshal payload error, uid: %v, cmd: %v, version: %v, err: %v", ctx.Uid(), ctx.Cmd(), ctx.Version(), err)
+	if m.decodeErrorHandler != nil {
+		m.decodeErrorHandler(ctx, err)
+		return
+	}
+	ctx.Error(es.BadRequest(ReasonDecodeFailed, "payload decode failed").WithCause(err))
+}
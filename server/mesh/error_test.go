@@ -0,0 +1,106 @@
+package mesh
+
+import (
+	"context"
+	"testing"
+
+	"github.com/byteweap/meta/component/broker"
+	"github.com/byteweap/meta/encoding/proto"
+	"github.com/byteweap/meta/envelope"
+	es "github.com/byteweap/meta/errors"
+	"github.com/byteweap/meta/internal/cluster"
+)
+
+// TestRpcErrorReply 验证 request-reply handler 返回的结构化错误写入回复消息头
+func TestRpcErrorReply(t *testing.T) {
+	mb := &mockBroker{}
+	m := New(Broker(mb))
+	m.RpcRouteX("findRoom", "v1", func(ctx *RpcContext, _ *envelope.Header) ([]byte, string, int) {
+		return ctx.Error(es.NotFound("ROOM_NOT_FOUND", "room not found").WithMetadata(map[string]string{"room": "7"}))
+	})
+	m.handlerRequestReplyMessage(&broker.Message{
+		Reply: "svc.reply",
+		Header: broker.Header{
+			"cmd":     []string{"findRoom"},
+			"version": []string{"v1"},
+		},
+	})
+
+	e := cluster.GetErrorBy(mb.replyHdr)
+	if e == nil || e.Code != 404 || e.Reason != "ROOM_NOT_FOUND" || e.Message != "room not found" || e.Metadata["room"] != "7" {
+		t.Fatalf("unexpected reply error: %v", e)
+	}
+
+	// Request 以状态码返回业务错误, error 仅表示传输错误
+	mb.reqResp = &broker.Message{Header: mb.replyHdr}
+	m.ctx = context.Background()
+	_, tip, code, err := m.Request("room", "findRoom", "v1", nil)
+	if code != 404 || tip != "room not found" || err != nil {
+		t.Fatalf("unexpected request result: %v %v %v", code, tip, err)
+	}
+
+	// Call 解析为 *errors.Error
+	_, err = Call[envelope.Header, envelope.Header](context.Background(), m, "room", "findRoom", "v1", nil, CallNode("room-1"))
+	if !es.Is(err, es.NotFound("ROOM_NOT_FOUND", "")) || es.FromError(err).Metadata["room"] != "7" {
+		t.Fatalf("unexpected call result: %v", err)
+	}
+}
+
+// TestContextError 验证结构化错误下发给客户端
+func TestContextError(t *testing.T) {
+	mb := &mockBroker{}
+	m := New(Broker(mb))
+	m.appName = "game"
+
+	header := cluster.BuildHeader(1001, cluster.Event_Business, "gate.reply", "gate", "game")
+	ctx := newContext(m, &broker.Message{Header: header}, &envelope.IMessage{Header: &envelope.Header{Seq: 3, Cmd: 1}})
+	defer ctx.release()
+	ctx.Error(es.Forbidden("NOT_OWNER", "not room owner"))
+
+	out := &envelope.OMessage{}
+	if err := proto.Unmarshal(mb.pubData, out); err != nil {
+		t.Fatalf("unmarshal response: %v", err)
+	}
+	if r := out.GetResult(); r.GetCode() != 403 || r.GetReason() != "NOT_OWNER" || r.GetTip() != "not room owner" {
+		t.Fatalf("unexpected result: %+v", r)
+	}
+}
+
+// TestRpcReplyClearsStaleError 验证复用请求消息头回复时不携带请求中残留的错误字段
+func TestRpcReplyClearsStaleError(t *testing.T) {
+	mb := &mockBroker{}
+	m := New(Broker(mb))
+	m.RpcRouteX("ok", "v1", func(*RpcContext, *envelope.Header) ([]byte, string, int) {
+		return nil, "ok", 200
+	})
+	m.RpcRouteX("fail", "v1", func(*RpcContext, *envelope.Header) ([]byte, string, int) {
+		return nil, "busy", 503
+	})
+	stale := func(cmd string) *broker.Message {
+		return &broker.Message{
+			Reply: "svc.reply",
+			Header: broker.Header{
+				"cmd":          []string{cmd},
+				"version":      []string{"v1"},
+				"code":         []string{"404"},
+				"reason":       []string{"ROOM_NOT_FOUND"},
+				"err-md-room":  []string{"7"},
+				"err-md-owner": []string{"1001"},
+			},
+		}
+	}
+
+	m.handlerRequestReplyMessage(stale("ok"))
+	if e := cluster.GetErrorBy(mb.replyHdr); e != nil {
+		t.Fatalf("success reply should not carry error: %v", e)
+	}
+	if mb.replyHdr.Get("reason") != "" || mb.replyHdr.Get("err-md-room") != "" {
+		t.Fatalf("stale error fields should be cleared: %v", mb.replyHdr)
+	}
+
+	m.handlerRequestReplyMessage(stale("fail"))
+	e := cluster.GetErrorBy(mb.replyHdr)
+	if e == nil || e.Code != 503 || e.Reason != "" || e.Message != "busy" || len(e.Metadata) != 0 {
+		t.Fatalf("unexpected reply error: %v", e)
+	}
+}
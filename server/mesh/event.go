@@ -0,0 +1,84 @@
+package mesh
+
+import (
+	"fmt"
+
+	"github.com/byteweap/meta/component/broker"
+	"github.com/byteweap/meta/internal/cluster"
+)
+
+// Event 系统事件, 除内置事件外可使用自定义事件, 如: mesh.Event("level_up")
+type Event = cluster.Event
+
+// 内置事件
+const (
+	EventOnline    = cluster.Event_Online    // 玩家上线(gate)
+	EventOffline   = cluster.Event_Offline   // 玩家掉线(gate)
+	EventReconnect = cluster.Event_Reconnect // 玩家重连(gate)
+	EventKicked    = cluster.Event_Kicked    // 玩家被踢下线(gate), 随后触发 EventOffline
+	EventBind      = cluster.Event_Bind      // 玩家绑定到本节点(本地)
+	EventUnbind    = cluster.Event_Unbind    // 玩家与本节点解除绑定(本地)
+)
+
+// EventHandler 事件处理器
+// ctx 携带 uid、来源服务、来源节点(FromNode, gate 事件为网关节点)与元数据(Metadata, 如令牌声明),
+// data 为事件数据, 内置事件为空; ctx 仅在 handler 执行期间有效, 异步使用须 Copy
+type EventHandler func(ctx *Context, data []byte)
+
+// On 订阅事件, 同一事件可注册多个处理器, 按注册顺序执行
+// 来自其它服务的事件与消息 handler 在同一执行器上串行执行;
+// 本地事件(EventBind/EventUnbind)在调用 Bind/Unbind 的 goroutine 中同步执行
+func (m *Mesh) On(event Event, handler EventHandler) {
+	if handler == nil {
+		return
+	}
+	m.emu.Lock()
+	defer m.emu.Unlock()
+	if m.eventHandlers == nil {
+		m.eventHandlers = make(map[Event][]EventHandler)
+	}
+	m.eventHandlers[event] = append(m.eventHandlers[event], handler)
+}
+
+// Emit 向其它服务发布自定义事件, 内置事件(EventOnline 等)与迁移事件由框架发布, 不能通过 Emit 发布
+// 目标节点的选择同 Call: CallNode > CallAnyNode > 玩家绑定节点 > 服务发现
+func (m *Mesh) Emit(service string, event Event, uid int64, data []byte, opts ...CallOption) error {
+	if cluster.IsSystemEvent(event) {
+		return fmt.Errorf("mesh emit reserved event: %q", event)
+	}
+	o := &callOptions{uid: uid}
+	for _, opt := range opts {
+		opt(o)
+	}
+	node, err := m.resolve(m.ctx, service, o)
+	if err != nil {
+		return err
+	}
+	header := cluster.BuildHeader(uid, event, "", m.appName, service)
+	cluster.SetFromNode(header, m.appID)
+	subject := cluster.Subject(m.opts.prefix, m.appName, service, node)
+	return m.opts.broker.Pub(m.ctx, subject, data, broker.PubHeader(header))
+}
+
+// fire 执行事件处理器
+func (m *Mesh) fire(msg *broker.Message) {
+	event := cluster.GetEventBy(msg.Header)
+	m.emu.RLock()
+	handlers := m.eventHandlers[event]
+	m.emu.RUnlock()
+	if len(handlers) == 0 {
+		return
+	}
+	c := newContext(m, msg, nil)
+	defer c.release()
+	for _, handler := range handlers {
+		handler(c, msg.Data)
+	}
+}
+
+// fireLocal 触发本地事件
+func (m *Mesh) fireLocal(uid int64, event Event) {
+	header := cluster.BuildHeader(uid, event, "", m.appName, m.appName)
+	cluster.SetFromNode(header, m.appID)
+	m.fire(&broker.Message{Header: header})
+}
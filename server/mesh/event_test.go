@@ -0,0 +1,80 @@
+package mesh
+
+import (
+	"context"
+	"testing"
+
+	"github.com/byteweap/meta/component/broker"
+	"github.com/byteweap/meta/internal/cluster"
+	"github.com/byteweap/meta/metadata"
+)
+
+// TestEventMultipleHandlers 验证同一事件的多个处理器按注册顺序执行, 并可读取来源节点与元数据
+func TestEventMultipleHandlers(t *testing.T) {
+	m := New(Broker(&mockBroker{}))
+	m.ctx = context.Background()
+
+	var calls []string
+	m.OnlineHandler(func(uid int64) {
+		calls = append(calls, "legacy")
+	})
+	m.On(EventOnline, func(ctx *Context, _ []byte) {
+		if ctx.Uid() != 1001 || ctx.FromNode() != "gate-1" || ctx.Metadata().Get("role") != "vip" {
+			t.Errorf("unexpected context: uid=%d node=%q md=%v", ctx.Uid(), ctx.FromNode(), ctx.Metadata())
+		}
+		calls = append(calls, "bus")
+	})
+
+	header := cluster.BuildHeader(1001, cluster.Event_Online, "", "gate", "game")
+	cluster.SetFromNode(header, "gate-1")
+	cluster.SetMetadata(header, metadata.New("role", "vip"))
+	m.handleTask(task{msg: &broker.Message{Header: header}})
+
+	if len(calls) != 2 || calls[0] != "legacy" || calls[1] != "bus" {
+		t.Fatalf("unexpected calls: %v", calls)
+	}
+}
+
+// TestEventCustomAndLocal 验证自定义事件的发布与接收, 以及绑定触发的本地事件
+func TestEventCustomAndLocal(t *testing.T) {
+	mb := &mockBroker{}
+	m := New(Broker(mb), Locator(&mockLocator{}))
+	m.ctx, m.appName, m.appID = context.Background(), "game", "game-1"
+
+	if err := m.Emit("rank", Event("level_up"), 1001, []byte("lv:10"), CallNode("rank-1")); err != nil {
+		t.Fatalf("emit: %v", err)
+	}
+	if mb.pubCalls != 1 || string(mb.pubData) != "lv:10" {
+		t.Fatalf("unexpected publish: %d %q", mb.pubCalls, mb.pubData)
+	}
+	for _, event := range []Event{"", cluster.Event_Business, EventOnline, EventOffline, EventReconnect, EventKicked, EventBind, EventUnbind, cluster.Event_Pause, cluster.Event_Resume, cluster.Event_Migrate} {
+		if err := m.Emit("rank", event, 1001, nil, CallNode("rank-1")); err == nil {
+			t.Fatalf("expected reserved event error: %q", event)
+		}
+	}
+	if mb.pubCalls != 1 {
+		t.Fatalf("reserved events should not be published: %d", mb.pubCalls)
+	}
+
+	var got string
+	m.On(Event("level_up"), func(ctx *Context, data []byte) {
+		got = ctx.FromService() + ":" + string(data)
+	})
+	m.handleTask(task{msg: &broker.Message{
+		Header: cluster.BuildHeader(1001, Event("level_up"), "", "rank", "game"),
+		Data:   []byte("lv:10"),
+	}})
+	if got != "rank:lv:10" {
+		t.Fatalf("unexpected custom event: %q", got)
+	}
+
+	var binds, unbinds int
+	m.On(EventBind, func(*Context, []byte) { binds++ })
+	m.On(EventUnbind, func(*Context, []byte) { unbinds++ })
+	_ = m.BindTo(1001, "game-1")
+	_ = m.BindTo(1001, "game-1")
+	_ = m.Unbind(1001)
+	if binds != 1 || unbinds != 1 {
+		t.Fatalf("unexpected local events: binds=%d unbinds=%d", binds, unbinds)
+	}
+}
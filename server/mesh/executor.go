@@ -0,0 +1,110 @@
+package mesh
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/byteweap/meta/component/broker"
+	"github.com/byteweap/meta/internal/cluster"
+)
+
+// task 执行器任务, broker 消息或回调函数二选一
+type task struct {
+	msg *broker.Message
+	fn  func()
+}
+
+// executor 消息执行器
+// 由若干 worker 组成, 每个 worker 拥有独立队列并串行执行任务,
+// 相同 key 的任务总是落在同一 worker 上, 因此同一玩家(或房间)的消息保持有序,
+// 不同 key 的任务并行执行; 只有一个 worker 时等价于串行执行
+type executor struct {
+	queues []chan task
+	next   atomic.Uint64 // 无 key 任务的轮询计数
+}
+
+func newExecutor(workers, size int) *executor {
+	workers = max(workers, 1)
+	queues := make([]chan task, workers)
+	for i := range queues {
+		queues[i] = make(chan task, size)
+	}
+	return &executor{queues: queues}
+}
+
+// workers worker 数量
+func (e *executor) workers() int {
+	return len(e.queues)
+}
+
+// queue 根据 key 选择 worker 队列, key 为 0 时轮询分配
+func (e *executor) queue(key uint64) chan task {
+	n := uint64(len(e.queues))
+	if n == 1 {
+		return e.queues[0]
+	}
+	if key == 0 {
+		key = e.next.Add(1)
+	}
+	return e.queues[key%n]
+}
+
+// submit 投递任务, 队列已满时阻塞直到入队或 ctx 结束
+func (e *executor) submit(ctx context.Context, key uint64, t task) bool {
+	select {
+	case e.queue(key) <- t:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// trySubmit 投递任务, 队列已满时立即返回 false
+func (e *executor) trySubmit(key uint64, t task) bool {
+	select {
+	case e.queue(key) <- t:
+		return true
+	default:
+		return false
+	}
+}
+
+// pending 所有 worker 队列中待处理的任务数
+func (e *executor) pending() int {
+	n := 0
+	for _, q := range e.queues {
+		n += len(q)
+	}
+	return n
+}
+
+// run 运行第 i 个 worker, 直到 ctx 结束
+// drain 关闭后处理完队列中已有的任务再退出
+func (e *executor) run(ctx context.Context, drain <-chan struct{}, i int, handle func(task)) {
+	q := e.queues[i]
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-drain:
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case t := <-q:
+					handle(t)
+				default:
+					return
+				}
+			}
+		case t := <-q:
+			handle(t)
+		}
+	}
+}
+
+// defaultDispatchKey 默认分发 key: 玩家 uid
+// request-reply 消息不携带 uid, 返回 0 即轮询分配
+func defaultDispatchKey(msg *broker.Message) uint64 {
+	return uint64(cluster.GetUidBy(msg.Header))
+}
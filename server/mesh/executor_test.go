@@ -0,0 +1,62 @@
+package mesh
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+// TestExecutorKeyOrdering 验证相同 key 的任务落在同一 worker 上并保持顺序
+func TestExecutorKeyOrdering(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const (
+		workers = 4
+		keys    = 16
+		perKey  = 200
+	)
+	exec := newExecutor(workers, 8)
+
+	var (
+		mu   sync.Mutex
+		seen = make(map[uint64][]int)
+		done sync.WaitGroup
+	)
+	done.Add(keys * perKey)
+	for i := range workers {
+		go exec.run(ctx, nil, i, func(t task) { t.fn() })
+	}
+	for n := range perKey {
+		for k := uint64(1); k <= keys; k++ {
+			exec.submit(ctx, k, task{fn: func() {
+				mu.Lock()
+				seen[k] = append(seen[k], n)
+				mu.Unlock()
+				done.Done()
+			}})
+		}
+	}
+	done.Wait()
+
+	for k, got := range seen {
+		for i, n := range got {
+			if n != i {
+				t.Fatalf("key %d out of order at %d: %v", k, i, n)
+			}
+		}
+	}
+}
+
+// TestExecutorRoundRobin 验证 key 为 0 的任务轮询分配到所有 worker
+func TestExecutorRoundRobin(t *testing.T) {
+	exec := newExecutor(3, 3)
+	for range 3 {
+		exec.submit(context.Background(), 0, task{})
+	}
+	for i, q := range exec.queues {
+		if len(q) != 1 {
+			t.Fatalf("worker %d queued %d tasks", i, len(q))
+		}
+	}
+}
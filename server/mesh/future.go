@@ -0,0 +1,196 @@
+package mesh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	es "github.com/byteweap/meta/errors"
+)
+
+// Future 异步请求结果
+type Future[T any] struct {
+	m    *Mesh
+	key  uint64
+	done chan struct{}
+
+	mu  sync.Mutex
+	val T
+	err error
+	cbs []func(T, error)
+}
+
+func newFuture[T any](m *Mesh, key uint64) *Future[T] {
+	return &Future[T]{m: m, key: key, done: make(chan struct{})}
+}
+
+// Done 请求完成时关闭
+func (f *Future[T]) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait 阻塞等待请求结果, ctx 仅限制等待时间, 不取消请求本身
+func (f *Future[T]) Wait(ctx context.Context) (T, error) {
+	select {
+	case <-f.done:
+		return f.val, f.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// Then 注册完成回调, 回调投递到 mesh 执行器中执行
+// 回调与 CallUid 指定玩家的消息在同一 worker 上串行执行, 访问游戏状态无需加锁;
+// 请求已完成时立即投递
+func (f *Future[T]) Then(fn func(T, error)) {
+	f.mu.Lock()
+	select {
+	case <-f.done:
+		f.mu.Unlock()
+		f.dispatch(fn)
+	default:
+		f.cbs = append(f.cbs, fn)
+		f.mu.Unlock()
+	}
+}
+
+// complete 设置结果并投递已注册的回调
+func (f *Future[T]) complete(val T, err error) {
+	f.mu.Lock()
+	f.val, f.err = val, err
+	cbs := f.cbs
+	f.cbs = nil
+	close(f.done)
+	f.mu.Unlock()
+
+	for _, fn := range cbs {
+		f.dispatch(fn)
+	}
+}
+
+func (f *Future[T]) dispatch(fn func(T, error)) {
+	f.m.post(f.key, func() {
+		fn(f.val, f.err)
+	})
+}
+
+// RequestAsync 非阻塞调用其它服务的 request-reply 路由, 返回未解码的响应数据
+// 参数与错误语义同 Call
+// ctx 为 handler 的 *Context/*RpcContext 时, 发起前复制其截止时间与元数据, handler 返回后请求不受影响;
+// 其它 ctx 原样使用, 其取消会中止请求
+func (m *Mesh) RequestAsync(ctx context.Context, service, cmd, version string, req any, opts ...CallOption) *Future[[]byte] {
+	f := newFuture[[]byte](m, callKeyOf(opts))
+	ctx, cancel := detach(ctx)
+	go func() {
+		defer cancel()
+		f.complete(m.call(ctx, service, cmd, version, req, opts))
+	}()
+	return f
+}
+
+// Go 非阻塞调用其它服务的 request-reply 路由, 参数与错误语义同 Call
+// ctx 的处理同 RequestAsync, 可直接传入 handler 的 *Context/*RpcContext
+//
+// 示例:
+//
+//	mesh.Go[pb.RankRequest, pb.RankResponse](ctx, m, "rank", "top", "v1", req, mesh.CallUid(uid)).
+//		Then(func(resp *pb.RankResponse, err error) { ... })
+func Go[Req, Resp any](ctx context.Context, m *Mesh, service, cmd, version string, req *Req, opts ...CallOption) *Future[*Resp] {
+	f := newFuture[*Resp](m, callKeyOf(opts))
+	ctx, cancel := detach(ctx)
+	go func() {
+		defer cancel()
+		f.complete(Call[Req, Resp](ctx, m, service, cmd, version, req, opts...))
+	}()
+	return f
+}
+
+// callKeyOf 异步回调的分发 key, 取 CallUid 指定的玩家
+func callKeyOf(opts []CallOption) uint64 {
+	o := &callOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.uid > 0 {
+		return uint64(o.uid)
+	}
+	return 0
+}
+
+// NodeReply 单个节点的响应
+type NodeReply[T any] struct {
+	Node string // 节点 ID
+	Resp *T     // 响应, 失败时为 nil
+	Err  error  // 错误信息
+}
+
+// Gather 向服务的所有节点发送同一请求并收集响应(scatter-gather)
+// quorum 为需要的成功响应数, <= 0 表示全部节点; 成功数达到 quorum 后立即返回,
+// 其余请求被取消; 整体超时由 CallTimeout/mesh.RequestTimeout 控制.
+// 返回已收到的响应(含失败节点), 成功数未达到 quorum 时同时返回错误:
+// 无可用节点返回 503(ReasonUnavailable), 超时返回 504(ReasonTimeout)
+//
+// 示例:
+//
+//	replies, err := mesh.Gather[pb.RankRequest, pb.RankResponse](ctx, m, "rank", "top", "v1", req, 0)
+func Gather[Req, Resp any](ctx context.Context, m *Mesh, service, cmd, version string, req *Req, quorum int, opts ...CallOption) ([]NodeReply[Resp], error) {
+	o := &callOptions{timeout: m.opts.callTimeout}
+	for _, opt := range opts {
+		opt(o)
+	}
+	sel, err := m.ensure(service)
+	if err != nil {
+		return nil, es.ServiceUnavailable(ReasonUnavailable, fmt.Sprintf("service %s unavailable", service)).WithCause(err)
+	}
+	nodes := sel.Nodes()
+	if len(nodes) == 0 {
+		return nil, es.ServiceUnavailable(ReasonUnavailable, fmt.Sprintf("service %s has no available node", service))
+	}
+	if quorum <= 0 || quorum > len(nodes) {
+		quorum = len(nodes)
+	}
+
+	// 未达到 quorum 的请求在返回后继续执行, 不能引用池化的 Context
+	ctx, release := detach(ctx)
+	defer release()
+	var cancel context.CancelFunc
+	if o.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, o.timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	results := make(chan NodeReply[Resp], len(nodes))
+	for _, node := range nodes {
+		callOpts := append(opts[:len(opts):len(opts)], CallNode(node.ID()), CallTimeout(0))
+		go func(id string) {
+			resp, err := Call[Req, Resp](ctx, m, service, cmd, version, req, callOpts...)
+			results <- NodeReply[Resp]{Node: id, Resp: resp, Err: err}
+		}(node.ID())
+	}
+
+	var (
+		replies = make([]NodeReply[Resp], 0, len(nodes))
+		succeed int
+		errs    []error
+	)
+	for range nodes {
+		select {
+		case r := <-results:
+			replies = append(replies, r)
+			if r.Err != nil {
+				errs = append(errs, fmt.Errorf("node %s: %w", r.Node, r.Err))
+				continue
+			}
+			if succeed++; succeed >= quorum {
+				return replies, nil
+			}
+		case <-ctx.Done():
+			return replies, es.GatewayTimeout(ReasonTimeout, fmt.Sprintf("gather %s %s.%s timeout, %d/%d replied", service, cmd, version, succeed, quorum)).WithCause(ctx.Err())
+		}
+	}
+	return replies, es.ServiceUnavailable(ReasonUnavailable, fmt.Sprintf("gather %s %s.%s quorum not reached, %d/%d replied", service, cmd, version, succeed, quorum)).WithCause(errors.Join(errs...))
+}
@@ -0,0 +1,138 @@
+package mesh
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/byteweap/meta/component/broker"
+	"github.com/byteweap/meta/component/selector"
+	"github.com/byteweap/meta/encoding/proto"
+	"github.com/byteweap/meta/envelope"
+	es "github.com/byteweap/meta/errors"
+	"github.com/byteweap/meta/internal/cluster"
+	"github.com/byteweap/meta/metadata"
+)
+
+func startExecutor(t *testing.T, m *Mesh) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	m.ctx, m.appName = ctx, "game"
+	for i := range m.exec.workers() {
+		go m.exec.run(ctx, nil, i, m.handleTask)
+	}
+}
+
+// TestGoThen 验证异步调用的结果与执行器回调
+func TestGoThen(t *testing.T) {
+	data, err := proto.Marshal(&envelope.Header{Seq: 7})
+	if err != nil {
+		t.Fatalf("marshal response: %v", err)
+	}
+	mb := &mockBroker{reqResp: &broker.Message{Data: data, Header: broker.Header{"code": []string{"200"}}}}
+	m := New(Broker(mb))
+	startExecutor(t, m)
+
+	f := Go[envelope.Header, envelope.Header](context.Background(), m, "rank", "top", "v1", nil, CallNode("rank-1"), CallUid(1001))
+	resp, err := f.Wait(context.Background())
+	if err != nil || resp.GetSeq() != 7 {
+		t.Fatalf("unexpected result: %+v, %v", resp, err)
+	}
+
+	done := make(chan uint64, 1)
+	f.Then(func(resp *envelope.Header, err error) {
+		done <- resp.GetSeq()
+	})
+	select {
+	case seq := <-done:
+		if seq != 7 {
+			t.Fatalf("unexpected callback seq: %d", seq)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("callback not executed")
+	}
+}
+
+// TestGoDetachesHandlerContext 验证 handler 返回并回收 Context 后, 异步请求仍沿用其截止时间与元数据
+// 需配合 -race 运行
+func TestGoDetachesHandlerContext(t *testing.T) {
+	type result struct {
+		header broker.Header
+		err    error
+	}
+	var (
+		release = make(chan struct{})
+		seen    = make(chan result, 1)
+	)
+	mb := &mockBroker{reqFunc: func(ctx context.Context, _ string, header broker.Header, _ []byte) (*broker.Message, error) {
+		<-release
+		seen <- result{header: header, err: ctx.Err()}
+		return &broker.Message{Header: broker.Header{"code": []string{"200"}}}, nil
+	}}
+	m := New(Broker(mb))
+	startExecutor(t, m)
+
+	deadline := time.Now().Add(time.Second).Truncate(time.Millisecond)
+	header := cluster.BuildHeader(1001, cluster.Event_Business, "", "gate", "game")
+	header.Set("cmd", "enter")
+	header.Set("version", "v1")
+	cluster.SetDeadline(header, deadline)
+	cluster.SetMetadata(header, metadata.New("trace-id", "t1"))
+
+	ctx := newRpcContext(m, &broker.Message{Header: header})
+	_ = ctx.Done() // 创建派生 context, 归还时被取消
+	f := Go[envelope.Header, envelope.Header](ctx, m, "rank", "top", "v1", nil, CallNode("rank-1"))
+	ctx.release() // handler 返回, Context 归还对象池
+	close(release)
+
+	r := <-seen
+	if r.err != nil {
+		t.Fatalf("request context should outlive handler context: %v", r.err)
+	}
+	if d, ok := cluster.GetDeadlineBy(r.header); !ok || !d.Equal(deadline) {
+		t.Fatalf("deadline not propagated: %v", r.header)
+	}
+	if cluster.GetMetadataBy(r.header).Get("trace-id") != "t1" {
+		t.Fatalf("metadata not propagated: %v", r.header)
+	}
+	if _, err := f.Wait(context.Background()); err != nil {
+		t.Fatalf("wait: %v", err)
+	}
+}
+
+// TestGatherQuorum 验证 scatter-gather 达到 quorum 后提前返回
+func TestGatherQuorum(t *testing.T) {
+	data, err := proto.Marshal(&envelope.Header{Seq: 1})
+	if err != nil {
+		t.Fatalf("marshal response: %v", err)
+	}
+	mb := &mockBroker{reqFunc: func(ctx context.Context, subject string, _ broker.Header, _ []byte) (*broker.Message, error) {
+		if strings.HasSuffix(subject, "rank-3") {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return &broker.Message{Data: data, Header: broker.Header{"code": []string{"200"}}}, nil
+	}}
+	m := New(Broker(mb))
+	startExecutor(t, m)
+	m.selectors.Store("rank", &mockSelector{nodes: []selector.Node{
+		selector.NewNode("rank-1", "rank", "", nil),
+		selector.NewNode("rank-2", "rank", "", nil),
+		selector.NewNode("rank-3", "rank", "", nil),
+	}})
+
+	replies, err := Gather[envelope.Header, envelope.Header](context.Background(), m, "rank", "top", "v1", nil, 2)
+	if err != nil {
+		t.Fatalf("gather: %v", err)
+	}
+	if len(replies) != 2 {
+		t.Fatalf("expected 2 replies, got %d", len(replies))
+	}
+
+	_, err = Gather[envelope.Header, envelope.Header](context.Background(), m, "rank", "top", "v1", nil, 0, CallTimeout(50*time.Millisecond))
+	if !es.IsGatewayTimeout(err) {
+		t.Fatalf("expected timeout, got %v", err)
+	}
+}
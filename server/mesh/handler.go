@@ -8,28 +8,81 @@ import (
 	"github.com/byteweap/meta/component/log"
 	"github.com/byteweap/meta/encoding/proto"
 	"github.com/byteweap/meta/envelope"
+	es "github.com/byteweap/meta/errors"
 )
 
 type MessageHandler func(*Mesh, *broker.Message, *envelope.IMessage)
 
 // Wrap 路由处理函数包装器
 // 统一处理网关消息,处理系统事件,自动解析业务参数 payload
+// 参数按 RegisterValidator 注册的规则或 Validatable 校验, 失败时回复 400 错误, 不调用 handler
 func Wrap[T any](handler func(*Context, *T)) MessageHandler {
 	return func(m *Mesh, msg *broker.Message, e *envelope.IMessage) {
 
 		ctx := newContext(m, msg, e)
 		defer ctx.release()
+		defer m.recoverContext(ctx)
 
-		if len(e.GetPayload()) == 0 {
-			handler(ctx, nil)
+		var payload *T
+		if len(e.GetPayload()) > 0 {
+			payload = new(T)
+			if err := proto.Unmarshal(e.GetPayload(), payload); err != nil {
+				m.decodeError(ctx, err)
+				return
+			}
+		}
+		if err := validatePtr(payload); err != nil {
+			ctx.Error(err)
+			return
+		}
+		handler(ctx, payload)
+	}
+}
+
+// WrapTyped 类型化路由处理函数包装器
+// 自动解析请求参数并序列化响应, 保证每条消息恰好回复一次:
+//   - 返回 error 时按 errors.FromError 转换为状态码回复, 非 *errors.Error 的错误按 500 处理
+//   - 返回 nil 响应时回复空 payload 的成功响应
+//   - handler 内已调用 ctx.OkResp/ctx.Error 时不再回复, 返回值被忽略
+//   - handler 异常时回复 500, 异常前已回复时不再回复
+func WrapTyped[Req, Resp any](handler func(*Context, *Req) (*Resp, error)) MessageHandler {
+	return func(m *Mesh, msg *broker.Message, e *envelope.IMessage) {
+
+		ctx := newContext(m, msg, e)
+		defer ctx.release()
+		defer m.recoverContext(ctx)
+
+		var req *Req
+		if len(e.GetPayload()) > 0 {
+			req = new(Req)
+			if err := proto.Unmarshal(e.GetPayload(), req); err != nil {
+				m.decodeError(ctx, err)
+				return
+			}
+		}
+		if err := validatePtr(req); err != nil {
+			ctx.Error(err)
 			return
 		}
-		var payload T
-		if err := proto.Unmarshal(e.GetPayload(), &payload); err != nil {
-			log.Errorf("mesh pub-sub unmarshal payload error: %v", err)
+		resp, err := handler(ctx, req)
+		if ctx.replied {
+			if resp != nil || err != nil {
+				log.Warnf("mesh typed handler already replied, result ignored, cmd: %v, version: %v", ctx.Cmd(), ctx.Version())
+			}
 			return
 		}
-		handler(ctx, &payload)
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+		var payload []byte
+		if resp != nil {
+			if payload, err = proto.Marshal(resp); err != nil {
+				ctx.Error(es.InternalServer(ReasonEncodeFailed, "response encode failed").WithCause(err))
+				return
+			}
+		}
+		ctx.okResp(payload)
 	}
 }
 
@@ -67,16 +120,21 @@ func adaptMessageHandler(handler any) (MessageHandler, error) {
 	return func(m *Mesh, msg *broker.Message, e *envelope.IMessage) {
 		ctx := newContext(m, msg, e)
 		defer ctx.release()
+		defer m.recoverContext(ctx)
 
 		callArg := reflect.Zero(argType)
 
 		if len(e.GetPayload()) > 0 {
 			callArg = reflect.New(argType.Elem())
 			if err := proto.Unmarshal(e.GetPayload(), callArg.Interface()); err != nil {
-				log.Errorf("mesh unmarshal payload error: %v", err)
+				m.decodeError(ctx, err)
 				return
 			}
 		}
+		if err := validateArg(callArg, argType); err != nil {
+			ctx.Error(err)
+			return
+		}
 		rv.Call([]reflect.Value{reflect.ValueOf(ctx), callArg})
 	}, nil
 }
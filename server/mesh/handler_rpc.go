@@ -2,16 +2,19 @@ package mesh
 
 import (
 	"fmt"
+	"net/http"
 	"reflect"
 
 	"github.com/byteweap/meta/component/broker"
-	"github.com/byteweap/meta/encoding/proto"
+	es "github.com/byteweap/meta/errors"
 )
 
 type RpcMessageHandler func(*Mesh, *broker.Message) ([]byte, string, int)
 
 // WrapRpc 路由处理函数包装器
-// 统一处理request-reply消息,处理系统事件,自动解析业务参数 payload
+// 统一处理request-reply消息,处理系统事件,自动解析业务参数 payload(使用 mesh.Codec 配置的编解码器)
+// 解析失败时回复 400(ReasonDecodeFailed), 不调用 handler
+// 参数按 RegisterValidator 注册的规则或 Validatable 校验, 失败时回复 400 错误, 不调用 handler
 // handler 返回:
 //   - []byte: 业务数据
 //   - string: 错误提示
@@ -22,17 +25,60 @@ func WrapRpc[T any](handler func(*RpcContext, *T) ([]byte, string, int)) RpcMess
 		ctx := newRpcContext(m, msg)
 		defer ctx.release()
 
-		if len(msg.Data) == 0 {
-			return handler(ctx, nil)
+		var payload *T
+		if len(msg.Data) > 0 {
+			payload = new(T)
+			if err := m.opts.codec.Unmarshal(msg.Data, payload); err != nil {
+				return ctx.decodeFailed(err)
+			}
+		}
+		if err := validatePtr(payload); err != nil {
+			return ctx.Error(err)
+		}
+		return handler(ctx, payload)
+	}
+}
+
+// WrapRpcTyped 类型化 request-reply 路由处理函数包装器
+// 使用 mesh.Codec 配置的编解码器自动解析请求参数并序列化响应, 调用方可直接使用 Call 获取类型化响应:
+//   - 返回 error 时按 errors.FromError 转换为状态码, 错误原因与附加信息随回复下发
+//   - 返回 nil 响应时回复空数据的成功响应
+func WrapRpcTyped[Req, Resp any](handler func(*RpcContext, *Req) (*Resp, error)) RpcMessageHandler {
+	return func(m *Mesh, msg *broker.Message) ([]byte, string, int) {
+
+		ctx := newRpcContext(m, msg)
+		defer ctx.release()
+
+		var req *Req
+		if len(msg.Data) > 0 {
+			req = new(Req)
+			if err := m.opts.codec.Unmarshal(msg.Data, req); err != nil {
+				return ctx.decodeFailed(err)
+			}
+		}
+		if err := validatePtr(req); err != nil {
+			return ctx.Error(err)
 		}
-		var payload T
-		if err := proto.Unmarshal(msg.Data, &payload); err != nil {
-			return nil, "unmarshal payload error", 500
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return ctx.Error(err)
 		}
-		return handler(ctx, &payload)
+		if resp == nil {
+			return nil, "ok", http.StatusOK
+		}
+		data, err := m.opts.codec.Marshal(resp)
+		if err != nil {
+			return ctx.Error(es.InternalServer(ReasonEncodeFailed, "response encode failed").WithCause(err))
+		}
+		return data, "ok", http.StatusOK
 	}
 }
 
+// decodeFailed 请求参数解码失败, 回复 400(ReasonDecodeFailed)
+func (ctx *RpcContext) decodeFailed(err error) ([]byte, string, int) {
+	return ctx.Error(es.BadRequest(ReasonDecodeFailed, "payload decode failed").WithCause(err))
+}
+
 // adaptRpcMessageHandler 将不同签名的 request handler 统一适配为 RpcMessageHandler
 // 原理:
 // 1) 若本身就是 RpcMessageHandler，直接返回
@@ -81,10 +127,13 @@ func adaptRpcMessageHandler(handler any) (RpcMessageHandler, error) {
 		callArg := reflect.Zero(argType)
 		if len(msg.Data) > 0 {
 			callArg = reflect.New(argType.Elem())
-			if err := proto.Unmarshal(msg.Data, callArg.Interface()); err != nil {
-				return nil, "unmarshal payload error", 500
+			if err := m.opts.codec.Unmarshal(msg.Data, callArg.Interface()); err != nil {
+				return ctx.decodeFailed(err)
 			}
 		}
+		if err := validateArg(callArg, argType); err != nil {
+			return ctx.Error(err)
+		}
 
 		out := rv.Call([]reflect.Value{reflect.ValueOf(ctx), callArg})
 		data := out[0].Interface().([]byte)
@@ -7,6 +7,8 @@ import (
 	"net/http"
 	"net/url"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/byteweap/meta"
 	"github.com/byteweap/meta/component/broker"
@@ -15,8 +17,8 @@ import (
 	"github.com/byteweap/meta/envelope"
 	es "github.com/byteweap/meta/errors"
 	"github.com/byteweap/meta/internal/cluster"
-	"github.com/byteweap/meta/pkg/async"
 	"github.com/byteweap/meta/pkg/conv"
+	"github.com/byteweap/meta/pkg/timewheel"
 	"github.com/byteweap/meta/server"
 )
 
@@ -27,16 +29,43 @@ type Mesh struct {
 	running bool
 
 	opts          *options
-	routes        sync.Map // key: cmd<<32|version (uint64), value: MessageHandler
-	requestRoutes sync.Map // key: cmd.version (string), value: RpcMessageHandler
+	exec          *executor            // 消息执行器
+	wheel         *timewheel.TimeWheel // 定时器时间轮
+	routes        sync.Map             // key: cmd<<32|version (uint64), value: MessageHandler
+	requestRoutes sync.Map             // key: cmd.version (string), value: RpcMessageHandler
+	routeInfos    sync.Map             // key: 同 routes/requestRoutes, value: RouteInfo
+	limits        sync.Map             // key: 同 routes/requestRoutes, value: *routeLimit
 
-	onlineHandler    func(uid int64) // 玩家上线
-	offlineHandler   func(uid int64) // 玩家掉线
-	reconnectHandler func(uid int64) // 玩家重连
+	vmu      sync.RWMutex
+	versions map[uint32][]uint32      // 已注册的版本(升序) key: cmd
+	policies map[uint32]VersionPolicy // 版本策略 key: cmd
+	upgrades map[uint64]upgrade       // 负载升级 key: cmd<<32|from
 
-	cancel context.CancelFunc
-	done   chan struct{}
-	mu     sync.Mutex
+	selectors *cluster.Selectors // 服务节点选择器
+
+	middlewares    []Middleware    // 全局 pub-sub 中间件
+	rpcMiddlewares []RpcMiddleware // 全局 request-reply 中间件
+
+	emu           sync.RWMutex
+	eventHandlers map[Event][]EventHandler // 事件处理器 key: 事件
+
+	notFoundHandler    func(ctx *Context)            // 路由不存在
+	decodeErrorHandler func(ctx *Context, err error) // payload 解码失败
+
+	bound   sync.Map // 已绑定到本节点的玩家 key: uid, value: struct{}
+	unbinds sync.Map // 掉线待解绑定时器 key: uid, value: *Timer
+
+	shutdownHandlers       []func(ctx context.Context)            // 停机钩子
+	playerShutdownHandlers []func(ctx context.Context, uid int64) // 玩家停机钩子
+
+	cancel   context.CancelFunc
+	halt     context.CancelFunc // 停止 worker, 排空超时时使用
+	done     chan struct{}
+	stopping chan struct{} // 关闭时开始两阶段停止
+	mu       sync.Mutex
+
+	panics atomic.Uint64 // handler 异常次数
+	shed   atomic.Uint64 // 被拒绝的消息数
 }
 
 var _ server.Server = (*Mesh)(nil)
@@ -47,7 +76,12 @@ func New(opts ...Option) *Mesh {
 	for _, opt := range opts {
 		opt(o)
 	}
-	return &Mesh{opts: o}
+	return &Mesh{
+		opts:      o,
+		exec:      newExecutor(o.workers, o.messageBufferSize),
+		wheel:     timewheel.New(o.timerTick),
+		selectors: cluster.NewSelectors(o.discovery, o.selectorFunc),
+	}
 }
 
 // Kind 返回服务类型
@@ -74,11 +108,14 @@ func (m *Mesh) Start(ctx context.Context) error {
 		return fmt.Errorf("mesh already running")
 	}
 	runCtx, cancel := context.WithCancel(ctx)
+	workCtx, halt := context.WithCancel(runCtx)
 	m.ctx = runCtx
 	m.cancel = cancel
+	m.halt = halt
 	m.appID = app.ID()
 	m.appName = app.Name()
 	m.done = make(chan struct{})
+	m.stopping = make(chan struct{})
 	m.running = true
 	m.mu.Unlock()
 
@@ -90,38 +127,61 @@ func (m *Mesh) Start(ctx context.Context) error {
 		}
 		m.running = false
 		m.cancel = nil
+		m.halt = nil
 		m.mu.Unlock()
 	}()
 
 	// 启动常驻协程
-	return m.loop()
+	return m.loop(workCtx)
 }
 
 // Stop 停止 Mesh 服务
+// 两阶段停止: 先取消 broker 订阅不再接收新消息, 再处理完执行器中已缓冲的消息(受 ctx 限制),
+// 然后依次执行玩家停机钩子与停机钩子, 最后退出;
+// ctx 结束时缓冲区中剩余的消息被丢弃, 待正在执行的 handler 返回、worker 退出后再执行停机钩子,
+// worker 在 ShutdownTimeout 内仍未退出时跳过停机钩子;
+// 由 meta.App 停止时, 注册中心注销先于 Stop 执行, 新流量不再路由到本节点
 func (m *Mesh) Stop(ctx context.Context) error {
 	m.mu.Lock()
 	if !m.running {
 		m.mu.Unlock()
 		return nil
 	}
-	cancel := m.cancel
+	cancel, halt := m.cancel, m.halt
 	done := m.done
+	select {
+	case <-m.stopping:
+	default:
+		close(m.stopping)
+	}
 	m.mu.Unlock()
 
-	if cancel != nil {
-		cancel()
-	}
-	if done == nil {
-		return nil
-	}
+	defer cancel()
+
+	var err error
 	select {
 	case <-done:
-		log.Infof("%s.%s server stop success", m.appName, m.appID)
-		return nil
 	case <-ctx.Done():
-		log.Warnf("%s.%s server stop timeout", m.appName, m.appID)
-		return ctx.Err()
+		log.Warnf("%s.%s server drain timeout, remaining messages dropped", m.appName, m.appID)
+		err = ctx.Err()
+		// 停止 worker 并等待其退出, 停机钩子不能与 handler 并发访问游戏状态
+		halt()
+		timer := time.NewTimer(m.opts.shutdownTimeout)
+		select {
+		case <-done:
+			timer.Stop()
+		case <-timer.C:
+			log.Errorf("%s.%s workers not exited in %v, shutdown handlers skipped", m.appName, m.appID, m.opts.shutdownTimeout)
+			return err
+		}
+	}
+	hctx, hcancel := context.WithTimeout(context.WithoutCancel(ctx), m.opts.shutdownTimeout)
+	m.shutdown(hctx)
+	hcancel()
+	if err == nil {
+		log.Infof("%s.%s server stop success", m.appName, m.appID)
 	}
+	return err
 }
 
 // Endpoint 返回服务监听地址
@@ -146,28 +206,46 @@ func requestRouteKey(cmd, version string) string {
 	return cmd + "." + version
 }
 
-// OnlineHandler 玩家上线事件处理器
+// OnlineHandler 玩家上线事件处理器, 等价于 On(EventOnline, ...)
 func (m *Mesh) OnlineHandler(handler func(uid int64)) {
+	m.onUid(EventOnline, handler)
+}
+
+// OfflineHandler 玩家掉线事件处理器, 等价于 On(EventOffline, ...)
+func (m *Mesh) OfflineHandler(handler func(uid int64)) {
+	m.onUid(EventOffline, handler)
+}
+
+// ReconnectHandler 玩家重连事件处理器, 等价于 On(EventReconnect, ...)
+func (m *Mesh) ReconnectHandler(handler func(uid int64)) {
+	m.onUid(EventReconnect, handler)
+}
+
+// NotFoundHandler 设置 pub-sub 路由不存在时的处理器
+// 默认回复 404(ReasonRouteNotFound)
+func (m *Mesh) NotFoundHandler(handler func(ctx *Context)) {
 	if handler == nil {
 		return
 	}
-	m.onlineHandler = handler
+	m.notFoundHandler = handler
 }
 
-// OfflineHandler 玩家掉线事件处理器
-func (m *Mesh) OfflineHandler(handler func(uid int64)) {
+// DecodeErrorHandler 设置 pub-sub 路由 payload 解码失败时的处理器
+// 默认回复 400(ReasonDecodeFailed)
+func (m *Mesh) DecodeErrorHandler(handler func(ctx *Context, err error)) {
 	if handler == nil {
 		return
 	}
-	m.offlineHandler = handler
+	m.decodeErrorHandler = handler
 }
 
-// ReconnectHandler 玩家重连事件处理器
-func (m *Mesh) ReconnectHandler(handler func(uid int64)) {
+func (m *Mesh) onUid(event Event, handler func(uid int64)) {
 	if handler == nil {
 		return
 	}
-	m.reconnectHandler = handler
+	m.On(event, func(ctx *Context, _ []byte) {
+		handler(ctx.Uid())
+	})
 }
 
 // RouteX 注册业务路由处理器(Gate pub-sub)
@@ -182,26 +260,33 @@ func (m *Mesh) ReconnectHandler(handler func(uid int64)) {
 // MessageHandler
 // 示例: mesh.RouteX(cmd, version, mesh.Wrap(EnterGame))
 //
+// mws 为该路由独有的中间件, 位于全局中间件内层
+//
 // 如果 handler 签名不合法，函数会 panic
 // 注意: 使用反射, 热点路由请使用 Route
-func (m *Mesh) RouteX(cmd, version uint32, handler any) {
+func (m *Mesh) RouteX(cmd, version uint32, handler any, mws ...Middleware) {
 	mh, err := adaptMessageHandler(handler)
 	if err != nil {
 		panic(err)
 	}
 	key := routeKey(cmd, version)
-	m.routes.Store(key, mh)
+	m.routes.Store(key, limitRoute(key, m.chain(mh, mws)))
+	m.addVersion(cmd, version)
+	m.describeRoute(cmd, version, handler, payloadType(handler))
 }
 
 // Route 注册业务路由处理器
 // 该方法要求显式传入 MessageHandler（通常通过 mesh.Wrap 构造）
 // 运行期不经过反射调用，适合高频热点路由
-func (m *Mesh) Route(cmd, version uint32, handler MessageHandler) {
+// mws 为该路由独有的中间件, 位于全局中间件内层
+func (m *Mesh) Route(cmd, version uint32, handler MessageHandler, mws ...Middleware) {
 	if handler == nil {
 		panic("mesh: handler is nil")
 	}
 	key := routeKey(cmd, version)
-	m.routes.Store(key, handler)
+	m.routes.Store(key, limitRoute(key, m.chain(handler, mws)))
+	m.addVersion(cmd, version)
+	m.describeRoute(cmd, version, handler, "")
 }
 
 // RpcRouteX 注册 request-reply 路由处理器
@@ -216,70 +301,132 @@ func (m *Mesh) Route(cmd, version uint32, handler MessageHandler) {
 // RpcMessageHandler
 // 示例: mesh.RpcRouteX(cmd, version, mesh.WrapRpc(HandleRequest))
 //
+// mws 为该路由独有的中间件, 位于全局中间件内层
+//
 // 如果 handler 签名不合法，函数会 panic
 // 注意: 使用反射, 热点路由请使用 RpcRoute
-func (m *Mesh) RpcRouteX(cmd, version string, handler any) {
+func (m *Mesh) RpcRouteX(cmd, version string, handler any, mws ...RpcMiddleware) {
 	mh, err := adaptRpcMessageHandler(handler)
 	if err != nil {
 		panic(err)
 	}
 	key := requestRouteKey(cmd, version)
-	m.requestRoutes.Store(key, mh)
+	m.requestRoutes.Store(key, limitRpcRoute(key, m.chainRpc(mh, mws)))
+	m.describeRpcRoute(cmd, version, handler, payloadType(handler))
 }
 
 // RpcRoute 注册 request-reply 路由处理器
 // 该方法要求显式传入 RpcMessageHandler（通常通过 mesh.WrapRpc 构造）
 // 运行期不经过反射调用，适合高频热点路由
-func (m *Mesh) RpcRoute(cmd, version string, handler RpcMessageHandler) {
+// mws 为该路由独有的中间件, 位于全局中间件内层
+func (m *Mesh) RpcRoute(cmd, version string, handler RpcMessageHandler, mws ...RpcMiddleware) {
 	if handler == nil {
 		panic("mesh: request-reply handler is nil")
 	}
 	key := requestRouteKey(cmd, version)
-	m.requestRoutes.Store(key, handler)
+	m.requestRoutes.Store(key, limitRpcRoute(key, m.chainRpc(handler, mws)))
+	m.describeRpcRoute(cmd, version, handler, "")
 }
 
 // loop 循环
-func (m *Mesh) loop() error {
+func (m *Mesh) loop(workCtx context.Context) error {
 
-	var (
-		o       = m.opts
-		subject = cluster.Subject(o.prefix, "*", m.appName, m.appID)
-		msgChan = make(chan *broker.Message, o.messageBufferSize)
-	)
+	exec := m.exec
 
 	// 订阅
-	sub, err := o.broker.Sub(m.ctx, subject, func(msg *broker.Message) {
-		select {
-		case msgChan <- msg:
-		case <-m.ctx.Done():
-		}
-	})
+	subs, err := m.subscribe(m.dispatch)
 	if err != nil {
 		return err
 	}
 
 	log.Infof("%s.%s server start success", m.appName, m.appID)
 
-	defer func() {
-		// 异常捕获,防止崩溃
-		async.Recover(func(r any) {
-			log.Errorf("mesh handler panic error: %v", r)
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			for _, sub := range subs {
+				if err := sub.Close(); err != nil {
+					log.Errorf("mesh close subscription error: %v", err)
+				}
+			}
 		})
-		if err := sub.Close(); err != nil {
-			log.Errorf("mesh close subscription error: %v", err)
-		}
+	}
+	defer func() {
+		unsubscribe()
+		m.stopWatchers()
 	}()
 
-	for {
-		select {
-		case <-m.ctx.Done():
-			return nil
-		case msg := <-msgChan:
-			if msg == nil {
-				continue
-			}
-			m.handlerMessage(msg)
+	// 定时器
+	go m.wheel.Run(m.ctx)
+
+	var (
+		wg    sync.WaitGroup
+		drain = make(chan struct{})
+	)
+	for i := range exec.workers() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			exec.run(workCtx, drain, i, m.handleTask)
+		}()
+	}
+
+	// 停止: 先取消订阅, 再处理完已缓冲的消息
+	select {
+	case <-workCtx.Done():
+	case <-m.stopping:
+		unsubscribe()
+		close(drain)
+	}
+	wg.Wait()
+	return nil
+}
+
+// subscribe 按订阅模式订阅节点主题与服务级主题
+func (m *Mesh) subscribe(handler broker.Handler) ([]broker.Subscription, error) {
+	var (
+		o    = m.opts
+		subs []broker.Subscription
+	)
+	closeAll := func() {
+		for _, sub := range subs {
+			_ = sub.Close()
+		}
+	}
+	if o.subscribeMode&SubscribeNode != 0 {
+		subject := cluster.Subject(o.prefix, "*", m.appName, m.appID)
+		sub, err := o.broker.Sub(m.ctx, subject, handler)
+		if err != nil {
+			return nil, err
 		}
+		subs = append(subs, sub)
+	}
+	if o.subscribeMode&SubscribeService != 0 {
+		queue := o.queueGroup
+		if queue == "" {
+			queue = m.appName
+		}
+		subject := cluster.ServiceSubject(o.prefix, "*", m.appName)
+		sub, err := o.broker.Sub(m.ctx, subject, handler, broker.SubQueue(queue))
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, nil
+}
+
+// handleTask 执行器任务处理
+// 每个任务独立捕获异常, 单个 handler 异常不影响 worker 继续处理后续消息
+func (m *Mesh) handleTask(t task) {
+	defer m.recoverTask()
+	if t.fn != nil {
+		t.fn()
+		return
+	}
+	if t.msg != nil {
+		m.handlerMessage(t.msg)
 	}
 }
 
@@ -295,16 +442,27 @@ func (m *Mesh) loop() error {
 //   - tip: 提示信息
 //   - code: 业务状态码
 //   - error: 错误信息
+//
+// 目标服务返回非 200 状态码时通过 code/tip 返回且 error 为 nil, error 仅表示请求未送达、超时等传输错误;
+// 需要结构化错误(错误原因与附加信息)时使用 Call, 失败时返回 *errors.Error
+// 不透传调用方的截止时间与元数据, 需要时使用 RequestContext
 func (m *Mesh) Request(subject, cmd, version string, data []byte) ([]byte, string, int, error) {
+	return m.RequestContext(m.ctx, subject, cmd, version, data)
+}
+
+// RequestContext 同 Request, ctx 的截止时间与元数据(metadata.FromContext)随请求透传给目标服务,
+// ctx 为 Context/RpcContext 时自动继承上游的值; ctx 结束时停止等待并返回其错误
+func (m *Mesh) RequestContext(ctx context.Context, subject, cmd, version string, data []byte) ([]byte, string, int, error) {
 	header := broker.Header{}
 	header.Set("cmd", cmd)
 	header.Set("version", version)
-	result, err := m.opts.broker.Request(m.ctx, subject, data, broker.RequestHeader(header))
+	propagate(ctx, header)
+	result, err := m.opts.broker.Request(ctx, subject, data, broker.RequestHeader(header))
 	if err != nil {
 		return nil, "", 0, err
 	}
-	code := result.Header.Get("code")
-	tip := result.Header.Get("tip")
+	code := result.Header.Get(cluster.FieldName_Code)
+	tip := result.Header.Get(cluster.FieldName_Tip)
 	return result.Data, tip, conv.Int(code), nil
 }
 
@@ -320,7 +478,7 @@ func (m *Mesh) sendMessage(subject, toService string, bytes []byte, uids ...int6
 	return err
 }
 
-// okReply 发送成功回复
+// okReply 发送成功回复, 复用请求消息头, 不回传请求的错误字段、截止时间与元数据
 func (m *Mesh) okReply(reqMsg *broker.Message, data []byte) error {
 	if reqMsg == nil {
 		return nil
@@ -332,13 +490,15 @@ func (m *Mesh) okReply(reqMsg *broker.Message, data []byte) error {
 	if header == nil {
 		header = broker.Header{}
 	}
-	header.Set("code", "200")
-	header.Set("tip", "ok")
+	cluster.ClearError(header)
+	cluster.ClearPropagation(header)
+	header.Set(cluster.FieldName_Code, "200")
+	header.Set(cluster.FieldName_Tip, "ok")
 	return m.opts.broker.Reply(m.ctx, reqMsg, data, broker.ReplyHeader(header))
 }
 
-// errReply 发送错误回复
-func (m *Mesh) errReply(reqMsg *broker.Message, code int, tip string) error {
+// errReply 发送错误回复, 状态码、提示信息、错误原因与附加信息写入回复消息头, 覆盖已有的错误字段, 不回传请求的截止时间与元数据
+func (m *Mesh) errReply(reqMsg *broker.Message, e *es.Error) error {
 	if reqMsg == nil {
 		return errors.New("request message is nil")
 	}
@@ -349,8 +509,8 @@ func (m *Mesh) errReply(reqMsg *broker.Message, code int, tip string) error {
 	if header == nil {
 		header = broker.Header{}
 	}
-	header.Set("code", conv.String(code))
-	header.Set("tip", tip)
+	cluster.ClearPropagation(header)
+	cluster.SetError(header, e)
 	return m.opts.broker.Reply(m.ctx, reqMsg, nil, broker.ReplyHeader(header))
 }
 
@@ -361,17 +521,29 @@ func (m *Mesh) handlerRequestReplyMessage(msg *broker.Message) {
 	}
 	header := msg.Header
 	if header == nil {
-		if err := m.errReply(msg, 100, "header is nil"); err != nil {
+		if err := m.errReply(msg, es.New(100, "", "header is nil")); err != nil {
 			log.Errorf("mesh [handlerRequestReplyMessage] reply error: %v", err)
 		}
 		return
 	}
+	// 玩家迁移
+	if cluster.GetEventBy(header) == cluster.Event_Migrate {
+		m.handleMigrate(msg)
+		return
+	}
 	cmd, version := header.Get("cmd"), header.Get("version")
+	// 调用方已超时, 不再处理
+	if deadline, ok := cluster.GetDeadlineBy(header); ok && !time.Now().Before(deadline) {
+		log.Warnf("mesh request-reply deadline exceeded, cmd: %v, version: %v, deadline: %v", cmd, version, deadline)
+		return
+	}
 	if handler, ok := m.requestRoutes.Load(requestRouteKey(cmd, version)); ok {
+		cluster.ClearError(header) // 移除请求中残留的错误字段, 回复时仅携带 handler 写入的错误
+		defer m.recoverRequest(msg)
 		data, tip, code := handler.(RpcMessageHandler)(m, msg)
 		m.replyRequestResult(msg, data, tip, code)
 	} else {
-		if err := m.errReply(msg, 404, fmt.Sprintf("cmd:%s version:%s not found", cmd, version)); err != nil {
+		if err := m.errReply(msg, es.NotFound(ReasonRouteNotFound, fmt.Sprintf("cmd:%s version:%s not found", cmd, version))); err != nil {
 			log.Errorf("mesh [handlerRequestReplyMessage] reply error: %v", err)
 		}
 	}
@@ -390,7 +562,12 @@ func (m *Mesh) replyRequestResult(msg *broker.Message, data []byte, tip string,
 	if code == 0 {
 		code = http.StatusInternalServerError
 	}
-	if err := m.errReply(msg, code, tip); err != nil {
+	// handler 通过 RpcContext.Error 返回时, 错误原因与附加信息已写入消息头
+	e := cluster.GetErrorBy(msg.Header)
+	if e == nil || e.Code != code {
+		e = es.New(code, "", tip)
+	}
+	if err := m.errReply(msg, e); err != nil {
 		log.Errorf("mesh request-reply err reply error: %v", err)
 	}
 }
@@ -404,29 +581,37 @@ func (m *Mesh) handlerPubSubMessage(msg *broker.Message) {
 	)
 
 	switch event {
-	case cluster.Event_Online:
-		if m.onlineHandler != nil {
-			m.onlineHandler(uid)
-		}
-	case cluster.Event_Offline:
-		if m.offlineHandler != nil {
-			m.offlineHandler(uid)
-		}
-	case cluster.Event_Reconnect:
-		if m.reconnectHandler != nil {
-			m.reconnectHandler(uid)
-		}
-	default:
+	case cluster.Event_Business, "":
 		e := &envelope.IMessage{}
 		if err := proto.Unmarshal(msg.Data, e); err != nil {
 			log.Errorf("mesh unmarshal Gate2MeshEnvelope error: %v", err)
 			return
 		}
 		header := e.GetHeader()
-		cmd, version := header.GetCmd(), header.GetVersion()
-		if handler, ok := m.routes.Load(routeKey(cmd, version)); ok {
-			handler.(MessageHandler)(m, msg, e)
+		handler, payload, err := m.matchRoute(header.GetCmd(), header.GetVersion(), e.GetPayload())
+		if err != nil {
+			ctx := newContext(m, msg, e)
+			ctx.Error(err)
+			ctx.release()
+			return
+		}
+		if handler == nil {
+			m.notFound(msg, e)
+			return
 		}
+		defer m.recoverMessage(msg, e)
+		e.Payload = payload
+		m.autoBind(uid, msg.Subject)
+		handler(m, msg, e)
+	case cluster.Event_Online, cluster.Event_Reconnect:
+		m.cancelUnbind(uid)
+		m.fire(msg)
+	case cluster.Event_Offline:
+		m.scheduleUnbind(uid)
+		m.forgetLimits(uid)
+		m.fire(msg)
+	default:
+		m.fire(msg)
 	}
 }
 
@@ -0,0 +1,104 @@
+package mesh
+
+// Middleware pub-sub 路由中间件
+// 用于在业务 handler 外层统一处理日志、鉴权、监控、参数校验等横切逻辑
+type Middleware func(MessageHandler) MessageHandler
+
+// RpcMiddleware request-reply 路由中间件
+type RpcMiddleware func(RpcMessageHandler) RpcMessageHandler
+
+// Use 注册全局 pub-sub 中间件
+// 中间件在注册路由时与 handler 组合, 因此须在注册路由前调用
+func (m *Mesh) Use(mws ...Middleware) {
+	m.middlewares = append(m.middlewares, mws...)
+}
+
+// UseRpc 注册全局 request-reply 中间件
+// 中间件在注册路由时与 handler 组合, 因此须在注册路由前调用
+func (m *Mesh) UseRpc(mws ...RpcMiddleware) {
+	m.rpcMiddlewares = append(m.rpcMiddlewares, mws...)
+}
+
+// Group 创建路由分组
+// 分组内注册的路由依次经过: 全局中间件 -> 分组中间件 -> 路由中间件 -> handler
+func (m *Mesh) Group() *Group {
+	return &Group{mesh: m}
+}
+
+// Group 路由分组, 分组内的路由共享一组中间件
+type Group struct {
+	mesh           *Mesh
+	middlewares    []Middleware
+	rpcMiddlewares []RpcMiddleware
+}
+
+// Use 注册分组 pub-sub 中间件, 须在注册路由前调用
+func (g *Group) Use(mws ...Middleware) *Group {
+	g.middlewares = append(g.middlewares, mws...)
+	return g
+}
+
+// UseRpc 注册分组 request-reply 中间件, 须在注册路由前调用
+func (g *Group) UseRpc(mws ...RpcMiddleware) *Group {
+	g.rpcMiddlewares = append(g.rpcMiddlewares, mws...)
+	return g
+}
+
+// Group 创建子分组, 子分组继承当前分组的中间件
+func (g *Group) Group() *Group {
+	return &Group{
+		mesh:           g.mesh,
+		middlewares:    append([]Middleware(nil), g.middlewares...),
+		rpcMiddlewares: append([]RpcMiddleware(nil), g.rpcMiddlewares...),
+	}
+}
+
+// Route 注册分组内的业务路由处理器, 参见 Mesh.Route
+func (g *Group) Route(cmd, version uint32, handler MessageHandler, mws ...Middleware) {
+	g.mesh.Route(cmd, version, handler, g.with(mws)...)
+}
+
+// RouteX 注册分组内的业务路由处理器, 参见 Mesh.RouteX
+func (g *Group) RouteX(cmd, version uint32, handler any, mws ...Middleware) {
+	g.mesh.RouteX(cmd, version, handler, g.with(mws)...)
+}
+
+// RpcRoute 注册分组内的 request-reply 路由处理器, 参见 Mesh.RpcRoute
+func (g *Group) RpcRoute(cmd, version string, handler RpcMessageHandler, mws ...RpcMiddleware) {
+	g.mesh.RpcRoute(cmd, version, handler, g.withRpc(mws)...)
+}
+
+// RpcRouteX 注册分组内的 request-reply 路由处理器, 参见 Mesh.RpcRouteX
+func (g *Group) RpcRouteX(cmd, version string, handler any, mws ...RpcMiddleware) {
+	g.mesh.RpcRouteX(cmd, version, handler, g.withRpc(mws)...)
+}
+
+func (g *Group) with(mws []Middleware) []Middleware {
+	return append(append([]Middleware(nil), g.middlewares...), mws...)
+}
+
+func (g *Group) withRpc(mws []RpcMiddleware) []RpcMiddleware {
+	return append(append([]RpcMiddleware(nil), g.rpcMiddlewares...), mws...)
+}
+
+// chain 按 全局 -> 传入 的顺序组合中间件, 先注册的中间件位于外层
+func (m *Mesh) chain(handler MessageHandler, mws []Middleware) MessageHandler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		handler = mws[i](handler)
+	}
+	for i := len(m.middlewares) - 1; i >= 0; i-- {
+		handler = m.middlewares[i](handler)
+	}
+	return handler
+}
+
+// chainRpc 按 全局 -> 传入 的顺序组合中间件, 先注册的中间件位于外层
+func (m *Mesh) chainRpc(handler RpcMessageHandler, mws []RpcMiddleware) RpcMessageHandler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		handler = mws[i](handler)
+	}
+	for i := len(m.rpcMiddlewares) - 1; i >= 0; i-- {
+		handler = m.rpcMiddlewares[i](handler)
+	}
+	return handler
+}
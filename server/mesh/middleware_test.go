@@ -0,0 +1,84 @@
+package mesh
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/byteweap/meta/component/broker"
+	"github.com/byteweap/meta/envelope"
+)
+
+func recordMiddleware(name string, trace *[]string) Middleware {
+	return func(next MessageHandler) MessageHandler {
+		return func(m *Mesh, msg *broker.Message, e *envelope.IMessage) {
+			*trace = append(*trace, name)
+			next(m, msg, e)
+		}
+	}
+}
+
+// TestMiddlewareOrder 验证中间件按 全局 -> 分组 -> 路由 的顺序执行
+func TestMiddlewareOrder(t *testing.T) {
+	m := New()
+
+	var trace []string
+	m.Use(recordMiddleware("global", &trace))
+	g := m.Group().Use(recordMiddleware("group", &trace))
+	sub := g.Group().Use(recordMiddleware("sub", &trace))
+
+	sub.RouteX(4001, 1, func(_ *Context, _ *envelope.Header) {
+		trace = append(trace, "handler")
+	}, recordMiddleware("route", &trace))
+	m.Route(4002, 1, Wrap(func(_ *Context, _ *envelope.Header) {
+		trace = append(trace, "handler")
+	}))
+
+	raw := mustBusinessMessage(t, 4001, 1, "game", &envelope.Header{})
+	invokeRouteHandler(t, mustLoadRouteHandler(t, m, 4001, 1), m, &broker.Message{Data: raw}, raw)
+	want := []string{"global", "group", "sub", "route", "handler"}
+	if !reflect.DeepEqual(trace, want) {
+		t.Fatalf("unexpected order: %v", trace)
+	}
+
+	// 父分组不受子分组中间件影响
+	trace = nil
+	raw = mustBusinessMessage(t, 4002, 1, "game", &envelope.Header{})
+	invokeRouteHandler(t, mustLoadRouteHandler(t, m, 4002, 1), m, &broker.Message{Data: raw}, raw)
+	if !reflect.DeepEqual(trace, []string{"global", "handler"}) {
+		t.Fatalf("unexpected order: %v", trace)
+	}
+}
+
+// TestRpcMiddlewareShortCircuit 验证 request-reply 中间件可以直接返回而不调用 handler
+func TestRpcMiddlewareShortCircuit(t *testing.T) {
+	mb := &mockBroker{}
+	m := New(Broker(mb))
+
+	deny := func(next RpcMessageHandler) RpcMessageHandler {
+		return func(m *Mesh, msg *broker.Message) ([]byte, string, int) {
+			if msg.Header.Get("token") == "" {
+				return nil, "unauthorized", 401
+			}
+			return next(m, msg)
+		}
+	}
+	called := false
+	m.Group().UseRpc(deny).RpcRouteX("secret", "v1", func(_ *RpcContext, _ *envelope.Header) ([]byte, string, int) {
+		called = true
+		return nil, "ok", 200
+	})
+
+	m.handlerRequestReplyMessage(&broker.Message{
+		Reply: "svc.reply",
+		Header: broker.Header{
+			"cmd":     []string{"secret"},
+			"version": []string{"v1"},
+		},
+	})
+	if called {
+		t.Fatalf("handler should not be called")
+	}
+	if mb.replyHdr.Get("code") != "401" || mb.replyHdr.Get("tip") != "unauthorized" {
+		t.Fatalf("unexpected reply header: %+v", mb.replyHdr)
+	}
+}
@@ -0,0 +1,180 @@
+package mesh
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/byteweap/meta/component/broker"
+	"github.com/byteweap/meta/component/log"
+	es "github.com/byteweap/meta/errors"
+	"github.com/byteweap/meta/internal/cluster"
+)
+
+const defaultGateName = "gate"
+
+// Migrator 玩家迁移钩子
+// 钩子在玩家所在 worker 上执行(按 uid 分发), 与该玩家的消息串行, 访问玩家状态无需加锁
+type Migrator interface {
+	// Export 源节点序列化玩家状态, 失败时中止迁移, 玩家留在源节点
+	Export(uid int64) ([]byte, error)
+	// Import 目标节点恢复玩家状态
+	Import(uid int64, state []byte) error
+	// Release 迁移成功后源节点释放玩家状态
+	Release(uid int64)
+}
+
+// MigrateOption Migrate 可选配置
+type MigrateOption func(*migrateOptions)
+
+type migrateOptions struct {
+	gate    string
+	timeout time.Duration
+}
+
+// MigrateGate 设置玩家所在网关的服务名, 默认: gate
+func MigrateGate(name string) MigrateOption {
+	return func(o *migrateOptions) {
+		if name != "" {
+			o.gate = name
+		}
+	}
+}
+
+// MigrateTimeout 设置迁移超时时间, 默认使用 mesh.RequestTimeout 配置
+// 网关暂停转发玩家消息的时间同样以此为限, 超时后自动恢复转发
+func MigrateTimeout(d time.Duration) MigrateOption {
+	return func(o *migrateOptions) {
+		if d > 0 {
+			o.timeout = d
+		}
+	}
+}
+
+// Migrate 将玩家从本节点迁移到同服务的 target 节点
+// 流程: 网关暂停转发该玩家发往本服务的消息 -> 本节点 Export 玩家状态 -> 目标节点 Import ->
+// 定位器改绑到目标节点 -> 本节点 Release -> 网关恢复转发并投递暂停期间缓存的消息.
+// 任一步骤失败时玩家留在本节点, 网关照常恢复转发; 玩家未连接网关时跳过暂停与恢复,
+// 可用于迁移以 uid 为 key 的非玩家实体(如房间).
+// 导出在玩家所在 worker 上执行, 不能在 handler 或定时器回调中同步调用, 否则相互等待直至超时
+func (m *Mesh) Migrate(ctx context.Context, uid int64, target string, opts ...MigrateOption) error {
+	var (
+		mg  = m.opts.migrator
+		loc = m.opts.locator
+	)
+	if mg == nil {
+		return es.ErrMigratorRequired
+	}
+	if loc == nil {
+		return es.ErrLocatorRequired
+	}
+	if target == "" || target == m.appID {
+		return fmt.Errorf("mesh migrate invalid target node: %q", target)
+	}
+	o := &migrateOptions{gate: defaultGateName, timeout: m.opts.callTimeout}
+	for _, opt := range opts {
+		opt(o)
+	}
+	ctx, cancel := context.WithTimeout(ctx, o.timeout)
+	defer cancel()
+
+	// 暂停网关转发
+	gateNode, err := loc.Node(ctx, uid, o.gate)
+	if err != nil {
+		return fmt.Errorf("mesh migrate get gate node error: %w", err)
+	}
+	if gateNode != "" {
+		if err = m.migrateRequest(ctx, uid, cluster.Event_Pause, o.gate, gateNode, nil); err != nil {
+			return fmt.Errorf("mesh migrate pause gate error: %w", err)
+		}
+		defer func() {
+			rctx, rcancel := context.WithTimeout(context.WithoutCancel(ctx), o.timeout)
+			defer rcancel()
+			if err := m.migrateRequest(rctx, uid, cluster.Event_Resume, o.gate, gateNode, nil); err != nil {
+				log.Errorf("mesh migrate resume gate error, uid: %v, gate: %v, err: %v", uid, gateNode, err)
+			}
+		}()
+	}
+
+	// 导出
+	var state []byte
+	if err = m.invoke(ctx, uid, func() (e error) {
+		state, e = mg.Export(uid)
+		return e
+	}); err != nil {
+		return fmt.Errorf("mesh migrate export error: %w", err)
+	}
+
+	// 导入
+	if err = m.migrateRequest(ctx, uid, cluster.Event_Migrate, m.appName, target, state); err != nil {
+		return fmt.Errorf("mesh migrate import error: %w", err)
+	}
+
+	// 改绑并释放
+	if err = m.BindTo(uid, target); err != nil {
+		return fmt.Errorf("mesh migrate rebind error: %w", err)
+	}
+	m.post(uint64(uid), func() {
+		mg.Release(uid)
+	})
+	log.Infof("mesh migrate success, uid: %v, from: %v, to: %v", uid, m.appID, target)
+	return nil
+}
+
+// invoke 于玩家所在 worker 上执行 fn 并等待结果
+func (m *Mesh) invoke(ctx context.Context, uid int64, fn func() error) error {
+	done := make(chan error, 1)
+	m.post(uint64(uid), func() {
+		done <- fn()
+	})
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// migrateRequest 向网关或目标节点发送迁移控制请求
+func (m *Mesh) migrateRequest(ctx context.Context, uid int64, event cluster.Event, service, node string, data []byte) error {
+	var (
+		header  = cluster.BuildHeader(uid, event, "", m.appName, service)
+		subject = cluster.Subject(m.opts.prefix, m.appName, service, node)
+	)
+	// 携带迁移截止时间, 网关据此决定暂停转发的最长时间
+	if deadline, ok := ctx.Deadline(); ok {
+		cluster.SetDeadline(header, deadline)
+	}
+	result, err := m.opts.broker.Request(ctx, subject, data, broker.RequestHeader(header))
+	if err != nil {
+		return err
+	}
+	if e := cluster.GetErrorBy(result.Header); e != nil {
+		return e
+	}
+	return nil
+}
+
+// handleMigrate 目标节点导入玩家状态
+func (m *Mesh) handleMigrate(msg *broker.Message) {
+	uid := cluster.GetUidBy(msg.Header)
+	if m.opts.migrator == nil {
+		if err := m.errReply(msg, es.New(http.StatusNotImplemented, ReasonMigrate, "migrator not configured")); err != nil {
+			log.Errorf("mesh migrate reply error: %v", err)
+		}
+		return
+	}
+	if err := m.opts.migrator.Import(uid, msg.Data); err != nil {
+		log.Errorf("mesh migrate import error, uid: %v, err: %v", uid, err)
+		if err = m.errReply(msg, es.InternalServer(ReasonMigrate, err.Error())); err != nil {
+			log.Errorf("mesh migrate reply error: %v", err)
+		}
+		return
+	}
+	m.cancelUnbind(uid)
+	m.markBound(uid)
+	if err := m.okReply(msg, nil); err != nil {
+		log.Errorf("mesh migrate reply error: %v", err)
+	}
+}
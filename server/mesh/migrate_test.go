@@ -0,0 +1,114 @@
+package mesh
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/byteweap/meta/component/broker"
+	"github.com/byteweap/meta/internal/cluster"
+)
+
+type mockMigrator struct {
+	mu       sync.Mutex
+	state    map[int64][]byte
+	released []int64
+}
+
+func (mg *mockMigrator) Export(uid int64) ([]byte, error) {
+	mg.mu.Lock()
+	defer mg.mu.Unlock()
+	return mg.state[uid], nil
+}
+
+func (mg *mockMigrator) Import(uid int64, state []byte) error {
+	mg.mu.Lock()
+	defer mg.mu.Unlock()
+	if mg.state == nil {
+		mg.state = make(map[int64][]byte)
+	}
+	mg.state[uid] = state
+	return nil
+}
+
+func (mg *mockMigrator) Release(uid int64) {
+	mg.mu.Lock()
+	defer mg.mu.Unlock()
+	delete(mg.state, uid)
+	mg.released = append(mg.released, uid)
+}
+
+// TestMigrate 验证迁移流程: 暂停网关 -> 导出 -> 目标导入 -> 改绑 -> 恢复网关
+func TestMigrate(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		events []cluster.Event
+		target = &mockMigrator{}
+	)
+	mb := &mockBroker{reqFunc: func(_ context.Context, subject string, header broker.Header, data []byte) (*broker.Message, error) {
+		mu.Lock()
+		events = append(events, cluster.GetEventBy(header))
+		mu.Unlock()
+		if cluster.GetEventBy(header) == cluster.Event_Pause {
+			if _, ok := cluster.GetDeadlineBy(header); !ok {
+				t.Errorf("pause request should carry migrate deadline")
+			}
+		}
+		if cluster.GetEventBy(header) == cluster.Event_Migrate {
+			if subject != cluster.Subject("meta", "game", "game", "game-2") {
+				t.Errorf("unexpected migrate subject: %s", subject)
+			}
+			_ = target.Import(cluster.GetUidBy(header), data)
+		}
+		return &broker.Message{Header: broker.Header{"code": []string{"200"}}}, nil
+	}}
+	loc := &mockLocator{nodes: map[int64]string{}}
+	source := &mockMigrator{state: map[int64][]byte{1001: []byte("hp:100")}}
+	m := New(Broker(mb), Locator(loc), Migration(source))
+	startExecutor(t, m)
+	m.appID = "game-1"
+	_ = loc.Bind(context.Background(), 1001, "gate", "gate-1")
+
+	if err := m.Migrate(context.Background(), 1001, "game-2"); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	want := []cluster.Event{cluster.Event_Pause, cluster.Event_Migrate, cluster.Event_Resume}
+	if len(events) != len(want) {
+		t.Fatalf("unexpected events: %v", events)
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Fatalf("unexpected events: %v", events)
+		}
+	}
+	if string(target.state[1001]) != "hp:100" {
+		t.Fatalf("state not imported: %q", target.state[1001])
+	}
+	if loc.node(1001) != "game-2" {
+		t.Fatalf("locator not rebound: %q", loc.node(1001))
+	}
+}
+
+// TestHandleMigrate 验证目标节点导入玩家状态并回复成功
+func TestHandleMigrate(t *testing.T) {
+	mb := &mockBroker{}
+	mg := &mockMigrator{}
+	m := New(Broker(mb), Migration(mg))
+
+	m.handleTask(task{msg: &broker.Message{
+		Reply:  "game.reply",
+		Header: cluster.BuildHeader(1001, cluster.Event_Migrate, "", "game", "game"),
+		Data:   []byte("hp:100"),
+	}})
+	if mb.replyCalls != 1 || mb.replyHdr.Get("code") != "200" {
+		t.Fatalf("unexpected reply: %d %+v", mb.replyCalls, mb.replyHdr)
+	}
+	if string(mg.state[1001]) != "hp:100" {
+		t.Fatalf("state not imported: %q", mg.state[1001])
+	}
+	if _, ok := m.bound.Load(int64(1001)); !ok {
+		t.Fatal("player not marked as bound")
+	}
+}
@@ -1,21 +1,59 @@
 package mesh
 
 import (
+	"time"
+
 	"github.com/byteweap/meta/component/broker"
 	"github.com/byteweap/meta/component/locator"
+	"github.com/byteweap/meta/component/registry"
+	"github.com/byteweap/meta/component/selector"
+	"github.com/byteweap/meta/encoding"
+	"github.com/byteweap/meta/encoding/proto"
 )
 
 const (
 	defaultPrefix            = "meta"
 	defaultMessageBufferSize = 256
+	defaultWorkers           = 1
+	defaultTimerTick         = 10 * time.Millisecond
+	defaultCallTimeout       = 5 * time.Second
+	defaultShutdownTimeout   = 10 * time.Second
+)
+
+// SubscribeMode 订阅模式, 可组合使用: SubscribeNode | SubscribeService
+type SubscribeMode int
+
+const (
+	// SubscribeNode 订阅节点主题, 消息由 gate/调用方通过玩家绑定或服务发现选定节点后投递, 适用于有状态服务
+	SubscribeNode SubscribeMode = 1 << iota
+	// SubscribeService 以 Queue Group 订阅服务级主题, 由 broker 在服务的所有节点间负载均衡,
+	// 适用于登录、邮件、商城等无状态服务, 调用方无需玩家绑定与选择器
+	SubscribeService
 )
 
 // options 选项
 type options struct {
-	prefix            string          // subject \ redis key 前缀
-	messageBufferSize int             // 消息缓冲区大小
-	locator           locator.Locator // 玩家位置定位器
-	broker            broker.Broker   // 消息传输代理
+	prefix            string                           // subject \ redis key 前缀
+	messageBufferSize int                              // 消息缓冲区大小(每个 worker)
+	workers           int                              // 消息处理 worker 数量
+	dispatchKey       func(msg *broker.Message) uint64 // 消息分发 key
+	timerTick         time.Duration                    // 定时器精度
+	locator           locator.Locator                  // 玩家位置定位器
+	broker            broker.Broker                    // 消息传输代理
+	discovery         registry.Registry                // 服务发现
+	selectorFunc      func() selector.Selector         // 选择器创建函数
+	codec             encoding.Codec                   // RPC 编解码器
+	callTimeout       time.Duration                    // Call 默认超时时间
+	subscribeMode     SubscribeMode                    // 订阅模式
+	queueGroup        string                           // 服务级主题的 Queue Group, 为空时使用 appName
+	autoBind          bool                             // 首条路由消息时自动绑定玩家到本节点
+	autoUnbind        bool                             // 玩家掉线后自动解绑
+	unbindGrace       time.Duration                    // 掉线后自动解绑的宽限期
+	migrator          Migrator                         // 玩家迁移钩子
+	publishRoutes     bool                             // 注册时发布路由摘要
+	versionPolicy     VersionPolicy                    // 默认路由版本策略
+	shedThreshold     int                              // 过载保护阈值(待处理消息数)
+	shutdownTimeout   time.Duration                    // 停机钩子超时时间
 }
 
 // Option 定义 Mesh 可选配置函数
@@ -26,6 +64,13 @@ func defaultOptions() *options {
 	return &options{
 		prefix:            defaultPrefix,
 		messageBufferSize: defaultMessageBufferSize,
+		workers:           defaultWorkers,
+		dispatchKey:       defaultDispatchKey,
+		timerTick:         defaultTimerTick,
+		codec:             encoding.GetCodec(proto.Name),
+		callTimeout:       defaultCallTimeout,
+		subscribeMode:     SubscribeNode,
+		shutdownTimeout:   defaultShutdownTimeout,
 	}
 }
 
@@ -38,7 +83,8 @@ func Prefix(prefix string) Option {
 	}
 }
 
-// MessageBufferSize 设置消息缓冲区大小, 默认 256
+// MessageBufferSize 设置消息缓冲区大小(每个 worker 的队列深度), 默认 256
+// 队列已满时 broker 订阅回调阻塞等待, 开启 ShedThreshold 后仅高优先级路由等待
 func MessageBufferSize(size int) Option {
 	return func(o *options) {
 		if size > 0 {
@@ -47,6 +93,36 @@ func MessageBufferSize(size int) Option {
 	}
 }
 
+// Workers 设置消息处理 worker 数量, 默认 1(所有消息串行处理)
+// 大于 1 时按 DispatchKey 将消息分配到 worker: 相同 key 的消息在同一 worker 上顺序执行,
+// 不同 key 的消息并行执行, 此时 handler 访问共享状态需自行保证并发安全
+func Workers(n int) Option {
+	return func(o *options) {
+		if n > 0 {
+			o.workers = n
+		}
+	}
+}
+
+// DispatchKey 设置消息分发 key 提取函数, 默认: 玩家 uid
+// 可按房间 ID 等业务 key 分发, 使同一房间的消息顺序执行; 返回 0 表示不要求顺序, 轮询分配
+func DispatchKey(fn func(msg *broker.Message) uint64) Option {
+	return func(o *options) {
+		if fn != nil {
+			o.dispatchKey = fn
+		}
+	}
+}
+
+// TimerTick 设置定时器精度(时间轮 tick), 默认 10ms
+func TimerTick(d time.Duration) Option {
+	return func(o *options) {
+		if d > 0 {
+			o.timerTick = d
+		}
+	}
+}
+
 // Locator 设置玩家位置定位器
 func Locator(locator locator.Locator) Option {
 	return func(o *options) {
@@ -64,3 +140,126 @@ func Broker(broker broker.Broker) Option {
 		}
 	}
 }
+
+// Discovery 设置服务发现, Call 未指定目标节点时通过服务发现选择节点
+func Discovery(discovery registry.Registry) Option {
+	return func(o *options) {
+		if discovery != nil {
+			o.discovery = discovery
+		}
+	}
+}
+
+// SelectorFunc 设置选择器创建函数
+func SelectorFunc(selectorFunc func() selector.Selector) Option {
+	return func(o *options) {
+		if selectorFunc != nil {
+			o.selectorFunc = selectorFunc
+		}
+	}
+}
+
+// Codec 设置 request-reply 负载的编解码器, 默认: proto
+// 用于 Call 编码请求、解码响应, 以及 WrapRpc/WrapRpcTyped/RpcRouteX 解码请求、WrapRpcTyped 编码响应,
+// 调用方与目标服务须配置相同的编解码器; 客户端经 gate 发送的 pub-sub 负载固定使用 proto
+func Codec(codec encoding.Codec) Option {
+	return func(o *options) {
+		if codec != nil {
+			o.codec = codec
+		}
+	}
+}
+
+// RequestTimeout 设置 Call 默认超时时间, 默认 5s
+func RequestTimeout(d time.Duration) Option {
+	return func(o *options) {
+		if d > 0 {
+			o.callTimeout = d
+		}
+	}
+}
+
+// Subscription 设置订阅模式, 默认: SubscribeNode
+//
+// 示例:
+//
+//	mesh.Subscription(mesh.SubscribeNode | mesh.SubscribeService)
+func Subscription(mode SubscribeMode) Option {
+	return func(o *options) {
+		if mode&(SubscribeNode|SubscribeService) != 0 {
+			o.subscribeMode = mode
+		}
+	}
+}
+
+// QueueGroup 设置服务级主题的 Queue Group 名称, 默认: 服务名(appName)
+// 仅在 SubscribeService 模式下生效, 同一 Queue Group 内的节点竞争消费
+func QueueGroup(name string) Option {
+	return func(o *options) {
+		if name != "" {
+			o.queueGroup = name
+		}
+	}
+}
+
+// AutoBind 设置是否在玩家首条路由消息时自动绑定到本节点, 默认: false
+// 绑定后 gate 将该玩家后续发往本服务的消息固定路由到本节点
+func AutoBind(enable bool) Option {
+	return func(o *options) {
+		o.autoBind = enable
+	}
+}
+
+// AutoUnbind 开启玩家掉线(Event_Offline)后自动解绑, grace 为宽限期
+// 宽限期内玩家上线或重连则取消解绑, 保证断线重连仍回到原节点; grace <= 0 表示立即解绑
+func AutoUnbind(grace time.Duration) Option {
+	return func(o *options) {
+		o.autoUnbind = true
+		o.unbindGrace = max(grace, 0)
+	}
+}
+
+// Migration 设置玩家迁移钩子, 开启 Migrate 及作为迁移目标节点接收玩家状态
+func Migration(migrator Migrator) Option {
+	return func(o *options) {
+		if migrator != nil {
+			o.migrator = migrator
+		}
+	}
+}
+
+// PublishRoutes 设置是否在服务注册时将 pub-sub 路由摘要写入实例元数据, 默认: false
+// gate 据此提前拒绝未知路由, 滚动升级时只将消息转发到实现了该路由的节点
+func PublishRoutes(enable bool) Option {
+	return func(o *options) {
+		o.publishRoutes = enable
+	}
+}
+
+// DefaultVersionPolicy 设置默认路由版本策略, 默认: VersionExact
+// 可通过 Mesh.SetVersionPolicy 按 cmd 单独设置
+func DefaultVersionPolicy(policy VersionPolicy) Option {
+	return func(o *options) {
+		o.versionPolicy = policy
+	}
+}
+
+// ShedThreshold 开启过载保护, n 为所有 worker 队列中待处理消息数的阈值, 默认: 0(不开启)
+// 待处理消息数达到 n 时拒绝 PriorityLow 路由, 队列已满时拒绝 PriorityHigh 以外的消息,
+// 被拒绝的消息回复 503 server busy, broker 订阅回调不再因队列已满而阻塞; 系统事件视为高优先级
+// 路由优先级通过 Mesh.SetRoutePolicy 设置
+func ShedThreshold(n int) Option {
+	return func(o *options) {
+		o.shedThreshold = max(n, 0)
+	}
+}
+
+// ShutdownTimeout 设置停机钩子的超时时间, 默认 10s
+// 停机钩子使用独立于 Stop 上下文的新上下文, 排空超时后等待 worker 退出的时间同样受此限制
+func ShutdownTimeout(d time.Duration) Option {
+	return func(o *options) {
+		if d > 0 {
+			o.shutdownTimeout = d
+		}
+	}
+}
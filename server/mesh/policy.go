@@ -0,0 +1,279 @@
+package mesh
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/byteweap/meta/component/broker"
+	"github.com/byteweap/meta/component/log"
+	"github.com/byteweap/meta/encoding/proto"
+	"github.com/byteweap/meta/envelope"
+	es "github.com/byteweap/meta/errors"
+	"github.com/byteweap/meta/internal/cluster"
+)
+
+const serverBusyTip = "server busy" // 过载时返回给调用方的提示信息
+
+// Priority 路由优先级, 节点过载时优先拒绝低优先级路由
+type Priority int8
+
+const (
+	// PriorityLow 低优先级, 待处理消息数达到 ShedThreshold 时拒绝
+	PriorityLow Priority = iota - 1
+	// PriorityNormal 普通优先级(默认), 队列已满时拒绝
+	PriorityNormal
+	// PriorityHigh 高优先级, 从不拒绝, 队列已满时等待入队
+	PriorityHigh
+)
+
+// RoutePolicy 路由策略
+type RoutePolicy struct {
+	MaxInFlight int      // 同时执行的最大数量, 超出时回复 server busy, 0 表示不限制
+	Rate        float64  // 每个玩家每秒允许的请求数(令牌桶), 超出时回复 429, 0 表示不限制; request-reply 消息不携带 uid, 所有调用方共享
+	Burst       int      // 令牌桶容量, <= 0 时取 max(1, Rate)
+	Priority    Priority // 优先级, 仅在开启 ShedThreshold 时生效
+}
+
+// SetRoutePolicy 设置 pub-sub 路由策略, 可在注册路由前后调用, 重复设置时覆盖
+//
+// 示例:
+//
+//	m.Route(cmd, version, mesh.Wrap(Chat))
+//	m.SetRoutePolicy(cmd, version, mesh.RoutePolicy{Rate: 2, Burst: 5, Priority: mesh.PriorityLow})
+func (m *Mesh) SetRoutePolicy(cmd, version uint32, policy RoutePolicy) {
+	m.limits.Store(routeKey(cmd, version), newRouteLimit(policy))
+}
+
+// SetRpcRoutePolicy 设置 request-reply 路由策略, 可在注册路由前后调用, 重复设置时覆盖
+func (m *Mesh) SetRpcRoutePolicy(cmd, version string, policy RoutePolicy) {
+	m.limits.Store(requestRouteKey(cmd, version), newRouteLimit(policy))
+}
+
+// Shed 返回因过载或路由策略被拒绝的消息数
+func (m *Mesh) Shed() uint64 {
+	return m.shed.Load()
+}
+
+// routeLimit 路由策略运行状态
+type routeLimit struct {
+	policy   RoutePolicy
+	inflight atomic.Int64
+
+	mu      sync.Mutex
+	buckets map[int64]*bucket // key: uid
+	swept   time.Time         // 上次清理空闲令牌桶的时间
+}
+
+func newRouteLimit(policy RoutePolicy) *routeLimit {
+	if policy.Rate > 0 && policy.Burst <= 0 {
+		policy.Burst = max(1, int(policy.Rate))
+	}
+	return &routeLimit{policy: policy}
+}
+
+// acquire 检查并发数与令牌桶, 通过后须调用 release
+func (l *routeLimit) acquire(uid int64) error {
+	p := l.policy
+	if p.MaxInFlight > 0 && l.inflight.Add(1) > int64(p.MaxInFlight) {
+		l.inflight.Add(-1)
+		return es.ServiceUnavailable(ReasonServerBusy, serverBusyTip)
+	}
+	if p.Rate > 0 && !l.allow(uid, time.Now()) {
+		l.release()
+		return es.TooManyRequests(ReasonRateLimited, "too many requests")
+	}
+	return nil
+}
+
+// release 释放并发数
+func (l *routeLimit) release() {
+	if l.policy.MaxInFlight > 0 {
+		l.inflight.Add(-1)
+	}
+}
+
+// allow 从玩家的令牌桶中取一个令牌
+func (l *routeLimit) allow(uid int64, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.buckets == nil {
+		l.buckets = make(map[int64]*bucket)
+		l.swept = now
+	}
+	l.sweep(now)
+	b, ok := l.buckets[uid]
+	if !ok {
+		b = &bucket{tokens: float64(l.policy.Burst), last: now}
+		l.buckets[uid] = b
+	}
+	return b.take(now, l.policy.Rate, float64(l.policy.Burst))
+}
+
+// sweep 每隔令牌桶补满所需的时间清理一次已补满的令牌桶, 补满的令牌桶与新建的等价
+// 避免未掉线(如 request-reply 调用方)或掉线事件丢失的玩家的令牌桶常驻内存, 调用方须持有 mu
+func (l *routeLimit) sweep(now time.Time) {
+	rate, burst := l.policy.Rate, float64(l.policy.Burst)
+	refill := time.Duration(burst / rate * float64(time.Second))
+	if now.Sub(l.swept) < max(refill, time.Second) {
+		return
+	}
+	l.swept = now
+	for uid, b := range l.buckets {
+		if b.full(now, rate, burst) {
+			delete(l.buckets, uid)
+		}
+	}
+}
+
+// forget 移除玩家的令牌桶
+func (l *routeLimit) forget(uid int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.buckets, uid)
+}
+
+// bucket 令牌桶, 按时间差惰性补充令牌
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// full 令牌桶补充后是否已满
+func (b *bucket) full(now time.Time, rate, burst float64) bool {
+	return b.tokens+now.Sub(b.last).Seconds()*rate >= burst
+}
+
+func (b *bucket) take(now time.Time, rate, burst float64) bool {
+	b.tokens = min(burst, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// forgetLimits 玩家掉线时移除其在各路由上的令牌桶
+func (m *Mesh) forgetLimits(uid int64) {
+	m.limits.Range(func(_, v any) bool {
+		v.(*routeLimit).forget(uid)
+		return true
+	})
+}
+
+// limitRoute 按路由策略限制 pub-sub handler, 策略于执行时查找, 未设置时直接执行
+func limitRoute(key uint64, handler MessageHandler) MessageHandler {
+	return func(m *Mesh, msg *broker.Message, e *envelope.IMessage) {
+		v, ok := m.limits.Load(key)
+		if !ok {
+			handler(m, msg, e)
+			return
+		}
+		l := v.(*routeLimit)
+		if err := l.acquire(cluster.GetUidBy(msg.Header)); err != nil {
+			m.reject(msg, e, es.FromError(err))
+			return
+		}
+		defer l.release()
+		handler(m, msg, e)
+	}
+}
+
+// limitRpcRoute 按路由策略限制 request-reply handler, 策略于执行时查找, 未设置时直接执行
+func limitRpcRoute(key string, handler RpcMessageHandler) RpcMessageHandler {
+	return func(m *Mesh, msg *broker.Message) ([]byte, string, int) {
+		v, ok := m.limits.Load(key)
+		if !ok {
+			return handler(m, msg)
+		}
+		l := v.(*routeLimit)
+		if err := l.acquire(cluster.GetUidBy(msg.Header)); err != nil {
+			m.shed.Add(1)
+			ctx := newRpcContext(m, msg)
+			defer ctx.release()
+			return ctx.Error(err)
+		}
+		defer l.release()
+		return handler(m, msg)
+	}
+}
+
+// dispatch broker 订阅回调, 将消息投递到执行器
+// 开启 ShedThreshold 时: 待处理消息数达到阈值拒绝低优先级路由, 队列已满时拒绝高优先级以外的消息,
+// 不再阻塞订阅回调
+func (m *Mesh) dispatch(msg *broker.Message) {
+	var (
+		key = m.opts.dispatchKey(msg)
+		t   = task{msg: msg}
+	)
+	threshold := m.opts.shedThreshold
+	if threshold <= 0 {
+		m.exec.submit(m.ctx, key, t)
+		return
+	}
+
+	pressured := m.exec.pending() >= threshold
+	if !pressured && m.exec.trySubmit(key, t) {
+		return
+	}
+	prio, e := m.priorityOf(msg)
+	switch {
+	case pressured && prio < PriorityNormal:
+		// 过载时拒绝低优先级路由
+	case m.exec.trySubmit(key, t):
+		return
+	case prio >= PriorityHigh:
+		m.exec.submit(m.ctx, key, t)
+		return
+	}
+	m.reject(msg, e, es.ServiceUnavailable(ReasonServerBusy, serverBusyTip))
+}
+
+// priorityOf 查找消息所属路由的优先级, 系统事件为高优先级
+// pub-sub 消息按版本策略解析到实际处理的路由(升级或回退)后查找, 同时返回解析出的 envelope
+func (m *Mesh) priorityOf(msg *broker.Message) (Priority, *envelope.IMessage) {
+	event := cluster.GetEventBy(msg.Header)
+	if event != cluster.Event_Business && event != "" {
+		return PriorityHigh, nil
+	}
+	var key any
+	var e *envelope.IMessage
+	if msg.Reply != "" {
+		key = requestRouteKey(msg.Header.Get("cmd"), msg.Header.Get("version"))
+	} else {
+		e = &envelope.IMessage{}
+		if err := proto.Unmarshal(msg.Data, e); err != nil {
+			return PriorityNormal, nil
+		}
+		cmd, version := e.GetHeader().GetCmd(), e.GetHeader().GetVersion()
+		if to, _, _, ok := m.resolveRoute(cmd, version); ok {
+			version = to
+		}
+		key = routeKey(cmd, version)
+	}
+	if v, ok := m.limits.Load(key); ok {
+		return v.(*routeLimit).policy.Priority, e
+	}
+	return PriorityNormal, e
+}
+
+// reject 拒绝消息并回复错误, pub-sub 消息回复给网关, request-reply 消息回复给调用方
+func (m *Mesh) reject(msg *broker.Message, e *envelope.IMessage, err *es.Error) {
+	m.shed.Add(1)
+	if msg.Reply != "" {
+		if rerr := m.errReply(msg, err); rerr != nil {
+			log.Errorf("mesh reject reply error: %v", rerr)
+		}
+		return
+	}
+	if e == nil {
+		e = &envelope.IMessage{}
+		if uerr := proto.Unmarshal(msg.Data, e); uerr != nil {
+			return
+		}
+	}
+	ctx := newContext(m, msg, e)
+	defer ctx.release()
+	ctx.Error(err)
+}
@@ -0,0 +1,160 @@
+package mesh
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/byteweap/meta/component/broker"
+	"github.com/byteweap/meta/encoding/proto"
+	"github.com/byteweap/meta/envelope"
+	"github.com/byteweap/meta/internal/cluster"
+)
+
+func lastResult(t *testing.T, mb *mockBroker) *envelope.Code {
+	t.Helper()
+	out := &envelope.OMessage{}
+	if err := proto.Unmarshal(mb.pubData, out); err != nil {
+		t.Fatalf("unmarshal response: %v", err)
+	}
+	return out.GetResult()
+}
+
+// TestRoutePolicyRateLimit 验证按玩家按路由的令牌桶限流, 掉线后重置
+func TestRoutePolicyRateLimit(t *testing.T) {
+	mb := &mockBroker{}
+	m := New(Broker(mb))
+	m.ctx = context.Background()
+	called := 0
+	m.Route(7001, 1, Wrap(func(_ *Context, _ *envelope.Header) { called++ }))
+	m.SetRoutePolicy(7001, 1, RoutePolicy{Rate: 0.001, Burst: 2})
+
+	send := func(uid int64) {
+		header := cluster.BuildHeader(uid, cluster.Event_Business, "gate.reply", "gate", "game")
+		m.handleTask(task{msg: &broker.Message{Header: header, Data: mustBusinessMessage(t, 7001, 1, "game", &envelope.Header{})}})
+	}
+	for range 3 {
+		send(1001)
+	}
+	if called != 2 || mb.pubCalls != 1 {
+		t.Fatalf("unexpected calls: handler=%d pubs=%d", called, mb.pubCalls)
+	}
+	if r := lastResult(t, mb); r.GetCode() != 429 || r.GetReason() != ReasonRateLimited {
+		t.Fatalf("unexpected response: %+v", r)
+	}
+
+	send(1002)
+	m.handleTask(task{msg: &broker.Message{Header: cluster.BuildHeader(1001, cluster.Event_Offline, "", "gate", "game")}})
+	send(1001)
+	if called != 4 || m.Shed() != 1 {
+		t.Fatalf("unexpected calls: handler=%d shed=%d", called, m.Shed())
+	}
+}
+
+// TestRoutePolicyMaxInFlight 验证并发数超出上限时拒绝
+func TestRoutePolicyMaxInFlight(t *testing.T) {
+	l := newRouteLimit(RoutePolicy{MaxInFlight: 1})
+	if err := l.acquire(1001); err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	if err := l.acquire(1002); err == nil {
+		t.Fatal("expected server busy")
+	}
+	l.release()
+	if err := l.acquire(1002); err != nil {
+		t.Fatalf("acquire after release: %v", err)
+	}
+}
+
+// TestRouteLimitSweep 验证令牌桶补满后被清理, 补满前保留
+func TestRouteLimitSweep(t *testing.T) {
+	l := newRouteLimit(RoutePolicy{Rate: 1, Burst: 2})
+	now := time.Now()
+	for uid := range int64(100) {
+		l.allow(uid, now)
+	}
+	l.allow(1000, now.Add(time.Second)) // 1s 未补满, 不清理
+	if n := len(l.buckets); n != 101 {
+		t.Fatalf("buckets swept before refilled: %d", n)
+	}
+	l.allow(1000, now.Add(3*time.Second))
+	if n := len(l.buckets); n != 1 {
+		t.Fatalf("refilled buckets not swept: %d", n)
+	}
+}
+
+// TestPriorityOfVersionFallback 验证按升级或回退后实际处理的路由查找优先级, 升级优先于回退
+func TestPriorityOfVersionFallback(t *testing.T) {
+	m := New(Broker(&mockBroker{}))
+	m.RouteX(7201, 1, func(_ *Context, _ *envelope.Header) {})
+	m.RouteX(7201, 3, func(_ *Context, _ *envelope.Header) {})
+	m.SetVersionPolicy(7201, VersionFallback)
+	m.Upgrade(7201, 2, 3, func(b []byte) ([]byte, error) { return b, nil })
+	m.SetRoutePolicy(7201, 1, RoutePolicy{Priority: PriorityLow})
+	m.SetRoutePolicy(7201, 3, RoutePolicy{Priority: PriorityHigh})
+
+	header := cluster.BuildHeader(1001, cluster.Event_Business, "gate.reply", "gate", "game")
+	for version, want := range map[uint32]Priority{1: PriorityLow, 2: PriorityHigh, 3: PriorityHigh, 4: PriorityHigh} {
+		msg := &broker.Message{Header: header, Data: mustBusinessMessage(t, 7201, version, "game", &envelope.Header{})}
+		if prio, _ := m.priorityOf(msg); prio != want {
+			t.Fatalf("version %d: priority %d, want %d", version, prio, want)
+		}
+	}
+}
+
+// TestShedThreshold 验证过载时拒绝低优先级路由, 队列已满时拒绝普通路由且不阻塞订阅回调
+func TestShedThreshold(t *testing.T) {
+	mb := &mockBroker{}
+	m := New(Broker(mb), MessageBufferSize(2), ShedThreshold(1))
+	m.ctx = context.Background()
+	m.SetRoutePolicy(7101, 1, RoutePolicy{Priority: PriorityLow})
+
+	header := cluster.BuildHeader(1001, cluster.Event_Business, "gate.reply", "gate", "game")
+	dispatch := func(cmd uint32) {
+		m.dispatch(&broker.Message{Header: header, Data: mustBusinessMessage(t, cmd, 1, "game", &envelope.Header{})})
+	}
+
+	dispatch(7101) // 未过载, 入队
+	dispatch(7101) // 待处理消息数达到阈值, 拒绝
+	if m.exec.pending() != 1 || mb.pubCalls != 1 {
+		t.Fatalf("low priority route not shed: pending=%d pubs=%d", m.exec.pending(), mb.pubCalls)
+	}
+	if r := lastResult(t, mb); r.GetCode() != 503 || r.GetReason() != ReasonServerBusy {
+		t.Fatalf("unexpected response: %+v", r)
+	}
+
+	dispatch(7102) // 普通优先级, 入队
+	dispatch(7102) // 队列已满, 拒绝
+	if m.exec.pending() != 2 || mb.pubCalls != 2 || m.Shed() != 2 {
+		t.Fatalf("normal route not shed on full queue: pending=%d pubs=%d shed=%d", m.exec.pending(), mb.pubCalls, m.Shed())
+	}
+
+	// 系统事件为高优先级, 队列已满时等待入队而非拒绝
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	m.ctx = ctx
+	m.dispatch(&broker.Message{Header: cluster.BuildHeader(1001, cluster.Event_Offline, "", "gate", "game")})
+	if mb.pubCalls != 2 || m.Shed() != 2 {
+		t.Fatalf("event should not be shed: pubs=%d shed=%d", mb.pubCalls, m.Shed())
+	}
+}
+
+// TestRpcRoutePolicy 验证 request-reply 路由限流回复 429
+func TestRpcRoutePolicy(t *testing.T) {
+	mb := &mockBroker{}
+	m := New(Broker(mb))
+	m.RpcRoute("7201", "1", WrapRpc(func(_ *RpcContext, _ *envelope.Header) ([]byte, string, int) {
+		return nil, "ok", 200
+	}))
+	m.SetRpcRoutePolicy("7201", "1", RoutePolicy{Rate: 0.001, Burst: 1})
+
+	for range 2 {
+		m.handlerRequestReplyMessage(&broker.Message{
+			Reply:  "svc.reply",
+			Header: broker.Header{"cmd": []string{"7201"}, "version": []string{"1"}},
+		})
+	}
+	if e := cluster.GetErrorBy(mb.replyHdr); mb.replyCalls != 2 || e == nil || e.Code != 429 || e.Reason != ReasonRateLimited {
+		t.Fatalf("unexpected reply: %d %+v", mb.replyCalls, mb.replyHdr)
+	}
+}
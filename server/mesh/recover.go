@@ -0,0 +1,80 @@
+package mesh
+
+import (
+	"runtime/debug"
+
+	"github.com/byteweap/meta/component/broker"
+	"github.com/byteweap/meta/component/log"
+	"github.com/byteweap/meta/envelope"
+	es "github.com/byteweap/meta/errors"
+	"github.com/byteweap/meta/internal/cluster"
+)
+
+const panicTip = "internal server error" // handler 异常时返回给调用方的提示信息
+
+// Panics 返回 handler 异常次数
+func (m *Mesh) Panics() uint64 {
+	return m.panics.Load()
+}
+
+// recoverMessage 捕获未经 Wrap 包装的 pub-sub handler(MessageHandler)异常, 记录日志并向客户端返回错误响应
+// 须以 defer 方式调用; 包装后的 handler 由 recoverContext 捕获
+func (m *Mesh) recoverMessage(msg *broker.Message, e *envelope.IMessage) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	header := e.GetHeader()
+	m.panicked(header.GetCmd(), header.GetVersion(), cluster.GetUidBy(msg.Header), r)
+
+	ctx := newContext(m, msg, e)
+	defer ctx.release()
+	ctx.Error(es.InternalServer(ReasonPanic, panicTip))
+}
+
+// recoverContext 捕获包装后的 pub-sub handler 异常, 记录日志, handler 尚未回复时返回错误响应
+// 保证每条消息最多回复一次, 须以 defer 方式调用
+func (m *Mesh) recoverContext(ctx *Context) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	m.panicked(ctx.Cmd(), ctx.Version(), ctx.Uid(), r)
+	if ctx.replied {
+		return
+	}
+	ctx.Error(es.InternalServer(ReasonPanic, panicTip))
+}
+
+// panicked 记录 pub-sub handler 异常
+func (m *Mesh) panicked(cmd, version uint32, uid int64, r any) {
+	m.panics.Add(1)
+	log.Errorf("mesh handler panic, cmd: %v, version: %v, uid: %v, error: %v\n%s", cmd, version, uid, r, debug.Stack())
+}
+
+// recoverRequest 捕获 request-reply handler 异常, 记录日志并回复错误
+// 须以 defer 方式调用
+func (m *Mesh) recoverRequest(msg *broker.Message) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	m.panics.Add(1)
+	log.Errorf("mesh request-reply handler panic, cmd: %v, version: %v, error: %v\n%s",
+		msg.Header.Get("cmd"), msg.Header.Get("version"), r, debug.Stack())
+
+	if err := m.errReply(msg, es.InternalServer(ReasonPanic, panicTip)); err != nil {
+		log.Errorf("mesh request-reply err reply error: %v", err)
+	}
+}
+
+// recoverTask 捕获其余任务(事件回调、定时任务等)异常, 仅记录日志
+// 须以 defer 方式调用
+func (m *Mesh) recoverTask() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	m.panics.Add(1)
+	log.Errorf("mesh task panic, error: %v\n%s", r, debug.Stack())
+}
@@ -0,0 +1,102 @@
+package mesh
+
+import (
+	"testing"
+
+	"github.com/byteweap/meta/component/broker"
+	"github.com/byteweap/meta/encoding/proto"
+	"github.com/byteweap/meta/envelope"
+	"github.com/byteweap/meta/internal/cluster"
+)
+
+// TestHandlerPanicIsolated 验证 handler 异常被单独捕获, 客户端收到错误响应且后续消息继续处理
+func TestHandlerPanicIsolated(t *testing.T) {
+	mb := &mockBroker{}
+	m := New(Broker(mb))
+
+	called := 0
+	m.RouteX(5001, 1, func(_ *Context, _ *envelope.Header) {
+		panic("boom")
+	})
+	m.RouteX(5002, 1, func(_ *Context, _ *envelope.Header) {
+		called++
+	})
+
+	header := cluster.BuildHeader(1001, cluster.Event_Business, "gate.reply", "gate", "game")
+	raw := mustBusinessMessage(t, 5001, 1, "game", &envelope.Header{})
+	m.handleTask(task{msg: &broker.Message{Header: header, Data: raw}})
+
+	if m.Panics() != 1 {
+		t.Fatalf("unexpected panics: %d", m.Panics())
+	}
+	if mb.pubCalls != 1 {
+		t.Fatalf("expected error response, got %d publishes", mb.pubCalls)
+	}
+	out := &envelope.OMessage{}
+	if err := proto.Unmarshal(mb.pubData, out); err != nil {
+		t.Fatalf("unmarshal response: %v", err)
+	}
+	if out.GetResult().GetCode() != 500 || out.GetHeader().GetCmd() != 5001 {
+		t.Fatalf("unexpected response: %+v", out)
+	}
+
+	raw = mustBusinessMessage(t, 5002, 1, "game", &envelope.Header{})
+	m.handleTask(task{msg: &broker.Message{Header: header, Data: raw}})
+	if called != 1 {
+		t.Fatalf("handler after panic not called")
+	}
+}
+
+// TestHandlerPanicAfterReply 验证 handler 回复后异常时不再回复错误, 每条消息只回复一次
+func TestHandlerPanicAfterReply(t *testing.T) {
+	mb := &mockBroker{}
+	m := New(Broker(mb))
+	m.RouteX(5003, 1, func(ctx *Context, _ *envelope.Header) {
+		ctx.OkResp()
+		panic("boom")
+	})
+	m.Route(5004, 1, WrapTyped(func(ctx *Context, _ *envelope.Header) (*envelope.Header, error) {
+		ctx.OkResp()
+		panic("boom")
+	}))
+
+	header := cluster.BuildHeader(1001, cluster.Event_Business, "gate.reply", "gate", "game")
+	for i, cmd := range []uint32{5003, 5004} {
+		raw := mustBusinessMessage(t, cmd, 1, "game", &envelope.Header{})
+		m.handleTask(task{msg: &broker.Message{Header: header, Data: raw}})
+
+		if m.Panics() != uint64(i+1) || mb.pubCalls != i+1 {
+			t.Fatalf("cmd %d: unexpected panics=%d pubs=%d", cmd, m.Panics(), mb.pubCalls)
+		}
+		out := &envelope.OMessage{}
+		if err := proto.Unmarshal(mb.pubData, out); err != nil {
+			t.Fatalf("unmarshal response: %v", err)
+		}
+		if out.GetResult() != nil {
+			t.Fatalf("cmd %d: unexpected error response: %+v", cmd, out.GetResult())
+		}
+	}
+}
+
+// TestRpcHandlerPanicReply 验证 request-reply handler 异常时回复 500
+func TestRpcHandlerPanicReply(t *testing.T) {
+	mb := &mockBroker{}
+	m := New(Broker(mb))
+
+	m.RpcRouteX("crash", "v1", func(_ *RpcContext, _ *envelope.Header) ([]byte, string, int) {
+		panic("boom")
+	})
+	m.handleTask(task{msg: &broker.Message{
+		Reply: "svc.reply",
+		Header: broker.Header{
+			"cmd":     []string{"crash"},
+			"version": []string{"v1"},
+		},
+	}})
+	if m.Panics() != 1 {
+		t.Fatalf("unexpected panics: %d", m.Panics())
+	}
+	if mb.replyCalls != 1 || mb.replyHdr.Get("code") != "500" {
+		t.Fatalf("unexpected reply header: %+v", mb.replyHdr)
+	}
+}
@@ -0,0 +1,18 @@
+package mesh
+
+import (
+	"github.com/byteweap/meta/component/log"
+	"github.com/byteweap/meta/component/selector"
+)
+
+// ensure 确保目标服务的选择器, 首次调用时创建并监听服务节点变化
+func (m *Mesh) ensure(service string) (selector.Selector, error) {
+	return m.selectors.Get(m.ctx, service)
+}
+
+// stopWatchers 停止所有服务节点监听
+func (m *Mesh) stopWatchers() {
+	if err := m.selectors.Stop(); err != nil {
+		log.Errorf("mesh stop watcher error: %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+package mesh
+
+import (
+	"cmp"
+	"reflect"
+	"runtime"
+	"slices"
+	"strconv"
+
+	"github.com/byteweap/meta/internal/cluster"
+)
+
+// RouteInfo 路由信息
+type RouteInfo struct {
+	Rpc     bool   // 是否为 request-reply 路由
+	Cmd     string // 命令字
+	Version string // 版本
+	Handler string // 处理函数名
+	Payload string // 请求类型, 通过 Route/RpcRoute 注册的已包装 handler 无法获取, 为空
+}
+
+// Routes 返回已注册的路由, pub-sub 路由在前, 按 cmd、version 排序
+func (m *Mesh) Routes() []RouteInfo {
+	var routes []RouteInfo
+	m.routeInfos.Range(func(_, v any) bool {
+		routes = append(routes, v.(RouteInfo))
+		return true
+	})
+	slices.SortFunc(routes, func(a, b RouteInfo) int {
+		if a.Rpc != b.Rpc {
+			if a.Rpc {
+				return 1
+			}
+			return -1
+		}
+		if !a.Rpc {
+			ac, _ := strconv.ParseUint(a.Cmd, 10, 32)
+			bc, _ := strconv.ParseUint(b.Cmd, 10, 32)
+			av, _ := strconv.ParseUint(a.Version, 10, 32)
+			bv, _ := strconv.ParseUint(b.Version, 10, 32)
+			return cmp.Or(cmp.Compare(ac, bc), cmp.Compare(av, bv))
+		}
+		return cmp.Or(cmp.Compare(a.Cmd, b.Cmd), cmp.Compare(a.Version, b.Version))
+	})
+	return routes
+}
+
+// Metadata 返回注册到服务实例的元数据, 实现 server.MetadataProvider
+// 开启 PublishRoutes 时包含 pub-sub 路由摘要, gate 据此提前拒绝未知路由并只选择实现了该路由的节点;
+// 摘要同时包含可升级到已注册版本的旧版本(Upgrade)与开启 VersionFallback 的 cmd 可回退的版本范围,
+// 因此版本策略与升级函数须在服务注册前设置; 摘要写入 meta-routes, 超出注册中心单值长度限制(512 字节)时分片存放于 meta-routes-1、meta-routes-2 ...
+func (m *Mesh) Metadata() map[string]string {
+	if !m.opts.publishRoutes {
+		return nil
+	}
+	var routes [][2]uint32
+	m.routes.Range(func(k, _ any) bool {
+		key := k.(uint64)
+		routes = append(routes, [2]uint32{uint32(key >> 32), uint32(key)})
+		return true
+	})
+
+	m.vmu.RLock()
+	defer m.vmu.RUnlock()
+	for key := range m.upgrades {
+		if _, registered := m.routes.Load(key); !registered && m.upgradable(key) {
+			routes = append(routes, [2]uint32{uint32(key >> 32), uint32(key)})
+		}
+	}
+	var fallbacks [][2]uint32
+	for cmd, versions := range m.versions {
+		policy, ok := m.policies[cmd]
+		if !ok {
+			policy = m.opts.versionPolicy
+		}
+		if policy == VersionFallback && len(versions) > 0 {
+			fallbacks = append(fallbacks, [2]uint32{cmd, versions[0]})
+		}
+	}
+	return cluster.RouteMetadata(cluster.RouteDigest(routes, fallbacks...))
+}
+
+// upgradable 升级链能否到达已注册的版本, 调用方须持有 vmu
+func (m *Mesh) upgradable(key uint64) bool {
+	cmd := uint32(key >> 32)
+	for range maxUpgradeHops {
+		up, ok := m.upgrades[key]
+		if !ok {
+			return false
+		}
+		key = routeKey(cmd, up.to)
+		if _, ok = m.routes.Load(key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// describeRoute 记录 pub-sub 路由信息
+func (m *Mesh) describeRoute(cmd, version uint32, handler any, payload string) {
+	m.routeInfos.Store(routeKey(cmd, version), RouteInfo{
+		Cmd:     strconv.FormatUint(uint64(cmd), 10),
+		Version: strconv.FormatUint(uint64(version), 10),
+		Handler: funcName(handler),
+		Payload: payload,
+	})
+}
+
+// describeRpcRoute 记录 request-reply 路由信息
+func (m *Mesh) describeRpcRoute(cmd, version string, handler any, payload string) {
+	m.routeInfos.Store(requestRouteKey(cmd, version), RouteInfo{
+		Rpc:     true,
+		Cmd:     cmd,
+		Version: version,
+		Handler: funcName(handler),
+		Payload: payload,
+	})
+}
+
+// funcName 函数名
+func funcName(fn any) string {
+	rv := reflect.ValueOf(fn)
+	if rv.Kind() != reflect.Func {
+		return ""
+	}
+	if f := runtime.FuncForPC(rv.Pointer()); f != nil {
+		return f.Name()
+	}
+	return ""
+}
+
+// payloadType 请求类型名, 仅支持 func(ctx, *T...) 形式的业务函数
+func payloadType(fn any) string {
+	rt := reflect.TypeOf(fn)
+	if rt == nil || rt.Kind() != reflect.Func || rt.NumIn() != 2 || rt.Name() != "" {
+		return ""
+	}
+	return rt.In(1).String()
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/byteweap/meta/component/broker"
 	"github.com/byteweap/meta/encoding/proto"
 	"github.com/byteweap/meta/envelope"
+	es "github.com/byteweap/meta/errors"
 	"github.com/byteweap/meta/internal/cluster"
 )
 
@@ -161,3 +162,93 @@ func invokeRouteHandler(t *testing.T, h MessageHandler, m *Mesh, msg *broker.Mes
 	}
 	h(m, msg, envy)
 }
+
+// TestNotFoundAndDecodeError 验证路由不存在与 payload 解码失败时的默认响应及自定义处理器
+func TestNotFoundAndDecodeError(t *testing.T) {
+	mb := &mockBroker{}
+	m := New(Broker(mb))
+	m.Route(6001, 1, Wrap(func(_ *Context, _ *envelope.Header) {
+		t.Fatal("handler should not be called on decode error")
+	}))
+
+	header := cluster.BuildHeader(1001, cluster.Event_Business, "gate.reply", "gate", "game")
+	result := func() *envelope.Code {
+		out := &envelope.OMessage{}
+		if err := proto.Unmarshal(mb.pubData, out); err != nil {
+			t.Fatalf("unmarshal response: %v", err)
+		}
+		return out.GetResult()
+	}
+
+	m.handleTask(task{msg: &broker.Message{Header: header, Data: mustBusinessMessage(t, 6002, 1, "game", &envelope.Header{})}})
+	if r := result(); r.GetCode() != 404 || r.GetReason() != ReasonRouteNotFound {
+		t.Fatalf("unexpected not found response: %+v", r)
+	}
+
+	raw, err := proto.Marshal(&envelope.IMessage{
+		Header:  &envelope.Header{Cmd: 6001, Version: 1},
+		Service: "game",
+		Payload: []byte{0xff, 0xff},
+	})
+	if err != nil {
+		t.Fatalf("marshal envelope: %v", err)
+	}
+	m.handleTask(task{msg: &broker.Message{Header: header, Data: raw}})
+	if r := result(); r.GetCode() != 400 || r.GetReason() != ReasonDecodeFailed {
+		t.Fatalf("unexpected decode error response: %+v", r)
+	}
+
+	var notFound, decodeErr int
+	m.NotFoundHandler(func(*Context) { notFound++ })
+	m.DecodeErrorHandler(func(*Context, error) { decodeErr++ })
+	m.handleTask(task{msg: &broker.Message{Header: header, Data: mustBusinessMessage(t, 6002, 1, "game", &envelope.Header{})}})
+	m.handleTask(task{msg: &broker.Message{Header: header, Data: raw}})
+	if notFound != 1 || decodeErr != 1 || mb.pubCalls != 2 {
+		t.Fatalf("custom handlers not used: notFound=%d decodeErr=%d pubs=%d", notFound, decodeErr, mb.pubCalls)
+	}
+}
+
+// TestWrapTyped 验证类型化 handler 自动回复响应与错误, 且每条消息仅回复一次
+func TestWrapTyped(t *testing.T) {
+	mb := &mockBroker{}
+	m := New(Broker(mb))
+	m.Route(6101, 1, WrapTyped(func(_ *Context, req *envelope.Header) (*envelope.Header, error) {
+		if req == nil {
+			return nil, es.NotFound("ROOM_NOT_FOUND", "room not found")
+		}
+		return &envelope.Header{Seq: req.GetSeq() + 1}, nil
+	}))
+	m.Route(6102, 1, WrapTyped(func(ctx *Context, _ *envelope.Header) (*envelope.Header, error) {
+		ctx.ErrResp(403)
+		return &envelope.Header{}, nil
+	}))
+
+	header := cluster.BuildHeader(1001, cluster.Event_Business, "gate.reply", "gate", "game")
+	response := func() *envelope.OMessage {
+		out := &envelope.OMessage{}
+		if err := proto.Unmarshal(mb.pubData, out); err != nil {
+			t.Fatalf("unmarshal response: %v", err)
+		}
+		return out
+	}
+
+	m.handleTask(task{msg: &broker.Message{Header: header, Data: mustBusinessMessage(t, 6101, 1, "game", &envelope.Header{Seq: 7})}})
+	resp := &envelope.Header{}
+	if err := proto.Unmarshal(response().GetPayload(), resp); err != nil || resp.GetSeq() != 8 {
+		t.Fatalf("unexpected response: %+v, err: %v", resp, err)
+	}
+
+	raw, err := proto.Marshal(&envelope.IMessage{Header: &envelope.Header{Cmd: 6101, Version: 1}, Service: "game"})
+	if err != nil {
+		t.Fatalf("marshal envelope: %v", err)
+	}
+	m.handleTask(task{msg: &broker.Message{Header: header, Data: raw}})
+	if r := response().GetResult(); r.GetCode() != 404 || r.GetReason() != "ROOM_NOT_FOUND" {
+		t.Fatalf("unexpected error response: %+v", r)
+	}
+
+	m.handleTask(task{msg: &broker.Message{Header: header, Data: mustBusinessMessage(t, 6102, 1, "game", &envelope.Header{})}})
+	if mb.pubCalls != 3 || response().GetResult().GetCode() != 403 {
+		t.Fatalf("expected exactly one reply, pubs=%d result=%+v", mb.pubCalls, response().GetResult())
+	}
+}
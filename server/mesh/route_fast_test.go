@@ -7,6 +7,7 @@ import (
 	"github.com/byteweap/meta/component/broker"
 	"github.com/byteweap/meta/encoding/proto"
 	"github.com/byteweap/meta/envelope"
+	"github.com/byteweap/meta/internal/cluster"
 )
 
 func TestRouteFastWithWrap(t *testing.T) {
@@ -70,3 +71,44 @@ func TestRequestRouteFastWithWrapRequest(t *testing.T) {
 		t.Fatalf("unexpected reply header: %+v", mb.replyHdr)
 	}
 }
+
+// TestRoutesIntrospection 验证路由信息查询与路由摘要
+func TestRoutesIntrospection(t *testing.T) {
+	m := New(PublishRoutes(true))
+	m.RouteX(2, 1, func(_ *Context, _ *envelope.Header) {})
+	m.Route(1, 1, Wrap(func(_ *Context, _ *envelope.Header) {}))
+	m.RpcRouteX("hello", "v1", func(_ *RpcContext, _ *envelope.Header) ([]byte, string, int) { return nil, "", 200 })
+
+	routes := m.Routes()
+	if len(routes) != 3 {
+		t.Fatalf("unexpected routes: %+v", routes)
+	}
+	if routes[0].Cmd != "1" || routes[1].Cmd != "2" || !routes[2].Rpc || routes[2].Cmd != "hello" {
+		t.Fatalf("unexpected order: %+v", routes)
+	}
+	if routes[1].Payload != "*envelope.Header" || routes[1].Handler == "" {
+		t.Fatalf("unexpected route info: %+v", routes[1])
+	}
+	if routes[2].Payload != "*envelope.Header" {
+		t.Fatalf("unexpected rpc route info: %+v", routes[2])
+	}
+	if got := m.Metadata()[cluster.MetadataKey_Routes]; got != "1.1,2.1" {
+		t.Fatalf("unexpected digest: %s", got)
+	}
+}
+
+// TestRouteDigestVersions 验证路由摘要包含可升级的旧版本与可回退的版本范围
+func TestRouteDigestVersions(t *testing.T) {
+	m := New(PublishRoutes(true))
+	noop := func(_ *Context, _ *envelope.Header) {}
+	m.RouteX(5, 2, noop)
+	m.RouteX(7, 3, noop)
+	m.SetVersionPolicy(5, VersionFallback)
+	m.Upgrade(7, 1, 2, func(p []byte) ([]byte, error) { return p, nil })
+	m.Upgrade(7, 2, 3, func(p []byte) ([]byte, error) { return p, nil })
+	m.Upgrade(8, 1, 2, func(p []byte) ([]byte, error) { return p, nil }) // 目标版本未注册
+
+	if got := m.Metadata()[cluster.MetadataKey_Routes]; got != "5.2,5.2+,7.1,7.2,7.3" {
+		t.Fatalf("unexpected digest: %s", got)
+	}
+}
@@ -6,8 +6,12 @@ import (
 	"testing"
 
 	"github.com/byteweap/meta/component/broker"
+	"github.com/byteweap/meta/encoding"
+	"github.com/byteweap/meta/encoding/json"
 	"github.com/byteweap/meta/encoding/proto"
 	"github.com/byteweap/meta/envelope"
+	es "github.com/byteweap/meta/errors"
+	"github.com/byteweap/meta/internal/cluster"
 )
 
 // TestAdaptRequestAutoWrapPayload 验证自动适配能正确反序列化请求参数
@@ -246,3 +250,78 @@ func TestRequestRouteInvalidHandlerPanic(t *testing.T) {
 
 	m.RpcRouteX("9999", "1", func() error { return nil })
 }
+
+// TestWrapRpcTyped 验证类型化 request-reply handler 的响应序列化与错误转换
+func TestWrapRpcTyped(t *testing.T) {
+	mb := &mockBroker{}
+	m := New(Broker(mb))
+	m.RpcRoute("2101", "1", WrapRpcTyped(func(_ *RpcContext, req *envelope.Header) (*envelope.Header, error) {
+		if req == nil {
+			return nil, es.NotFound("ROOM_NOT_FOUND", "room not found")
+		}
+		return &envelope.Header{Seq: req.GetSeq() + 1}, nil
+	}))
+	header := func() broker.Header {
+		return broker.Header{"cmd": []string{"2101"}, "version": []string{"1"}}
+	}
+
+	raw, err := proto.Marshal(&envelope.Header{Seq: 7})
+	if err != nil {
+		t.Fatalf("marshal payload: %v", err)
+	}
+	m.handlerRequestReplyMessage(&broker.Message{Reply: "svc.reply", Header: header(), Data: raw})
+	resp := &envelope.Header{}
+	if err = proto.Unmarshal(mb.replyData, resp); err != nil || resp.GetSeq() != 8 || mb.replyHdr.Get("code") != "200" {
+		t.Fatalf("unexpected reply: %+v %+v, err: %v", resp, mb.replyHdr, err)
+	}
+
+	m.handlerRequestReplyMessage(&broker.Message{Reply: "svc.reply", Header: header()})
+	if e := cluster.GetErrorBy(mb.replyHdr); e == nil || e.Code != 404 || e.Reason != "ROOM_NOT_FOUND" {
+		t.Fatalf("unexpected error reply: %+v", mb.replyHdr)
+	}
+
+	m.handlerRequestReplyMessage(&broker.Message{Reply: "svc.reply", Header: header(), Data: []byte{0xff, 0xff}})
+	if e := cluster.GetErrorBy(mb.replyHdr); e == nil || e.Code != 400 || e.Reason != ReasonDecodeFailed {
+		t.Fatalf("unexpected decode error reply: %+v", mb.replyHdr)
+	}
+}
+
+// TestRpcDecodeFailed 验证各 request-reply 包装器解码失败时回复 400(ReasonDecodeFailed), 不调用 handler
+func TestRpcDecodeFailed(t *testing.T) {
+	m := New()
+	raw := func(*RpcContext, *envelope.Header) ([]byte, string, int) {
+		t.Error("handler should not be called")
+		return nil, "ok", 200
+	}
+	handlers := map[string]RpcMessageHandler{
+		"WrapRpc": WrapRpc(raw),
+		"adapt":   mustAdaptRequestHandler(t, raw),
+		"WrapRpcTyped": WrapRpcTyped(func(*RpcContext, *envelope.Header) (*envelope.Header, error) {
+			t.Error("handler should not be called")
+			return nil, nil
+		}),
+	}
+	for name, h := range handlers {
+		msg := &broker.Message{Reply: "svc.reply", Header: broker.Header{}, Data: []byte{0xff}}
+		_, _, code := h(m, msg)
+		if e := cluster.GetErrorBy(msg.Header); code != 400 || e == nil || e.Reason != ReasonDecodeFailed {
+			t.Fatalf("%s: unexpected result: %d %v", name, code, e)
+		}
+	}
+}
+
+// TestRpcUsesCodec 验证 request-reply 包装器使用 mesh.Codec 配置的编解码器
+func TestRpcUsesCodec(t *testing.T) {
+	m := New(Codec(encoding.GetCodec(json.Name)))
+	h := WrapRpcTyped(func(_ *RpcContext, req *envelope.Header) (*envelope.Header, error) {
+		return &envelope.Header{Seq: req.GetSeq() + 1}, nil
+	})
+	data, _, code := h(m, &broker.Message{Reply: "svc.reply", Header: broker.Header{}, Data: []byte(`{"seq":7}`)})
+	if code != 200 {
+		t.Fatalf("unexpected code: %d", code)
+	}
+	resp := &envelope.Header{}
+	if err := encoding.GetCodec(json.Name).Unmarshal(data, resp); err != nil || resp.GetSeq() != 8 {
+		t.Fatalf("unexpected response: %s, %v", data, err)
+	}
+}
@@ -0,0 +1,152 @@
+package mesh
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/byteweap/meta/component/log"
+	"github.com/byteweap/meta/pkg/timewheel"
+)
+
+// Timer 定时器句柄
+type Timer struct {
+	mu      sync.Mutex
+	t       *timewheel.Timer
+	stopped atomic.Bool
+}
+
+// Stop 取消定时器
+// 在执行器中调用时保证之后不会再执行回调
+func (t *Timer) Stop() {
+	t.stopped.Store(true)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.t != nil {
+		t.t.Stop()
+	}
+}
+
+// set 设置当前时间轮定时器, 已取消时立即停止
+func (t *Timer) set(wt *timewheel.Timer) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.t = wt
+	if t.stopped.Load() {
+		wt.Stop()
+	}
+}
+
+// TimerOption 定时器可选配置
+type TimerOption func(*timerOptions)
+
+type timerOptions struct {
+	key uint64
+}
+
+// TimerKey 设置定时器回调的分发 key
+// 回调与相同 DispatchKey 的消息在同一 worker 上串行执行, 例如传入玩家 uid 或房间 id;
+// 未设置时回调轮询分配到 worker, 仅 Workers(1) 时与所有消息串行
+func TimerKey(key uint64) TimerOption {
+	return func(o *timerOptions) {
+		o.key = key
+	}
+}
+
+func timerKeyOf(opts []TimerOption) uint64 {
+	o := &timerOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o.key
+}
+
+// post 将回调投递到执行器
+func (m *Mesh) post(key uint64, fn func()) {
+	m.exec.submit(m.ctx, key, task{fn: fn})
+}
+
+// postTimer 于时间轮回调中投递定时器回调, 不阻塞时间轮
+// worker 队列已满时于下一个 tick 重试, 定时器取消或 mesh 停止后放弃
+func (m *Mesh) postTimer(timer *Timer, key uint64, fn func(), retries int) {
+	if m.exec.trySubmit(key, task{fn: fn}) || timer.stopped.Load() || m.ctx.Err() != nil {
+		return
+	}
+	if retries == 0 {
+		log.Warnf("mesh timer callback delayed, worker queue is full, key: %v", key)
+	}
+	timer.set(m.wheel.AfterFunc(m.wheel.Tick(), func() {
+		m.postTimer(timer, key, fn, retries+1)
+	}))
+}
+
+// AfterFunc 在 d 之后于消息执行器中执行 fn
+// 与消息 handler 在同一执行器上串行执行, 访问游戏状态无需加锁
+func (m *Mesh) AfterFunc(d time.Duration, fn func(), opts ...TimerOption) *Timer {
+	var (
+		key   = timerKeyOf(opts)
+		timer = &Timer{}
+	)
+	timer.set(m.wheel.AfterFunc(d, func() {
+		m.postTimer(timer, key, func() {
+			if !timer.stopped.Load() {
+				fn()
+			}
+		}, 0)
+	}))
+	return timer
+}
+
+// Every 每隔 d 于消息执行器中执行 fn
+// 下一次计时从本次回调执行完毕开始(固定间隔), 适合超时检测、定期存盘等
+func (m *Mesh) Every(d time.Duration, fn func(), opts ...TimerOption) *Timer {
+	var (
+		key      = timerKeyOf(opts)
+		timer    = &Timer{}
+		schedule func()
+	)
+	schedule = func() {
+		timer.set(m.wheel.AfterFunc(d, func() {
+			m.postTimer(timer, key, func() {
+				if timer.stopped.Load() {
+					return
+				}
+				fn()
+				schedule()
+			}, 0)
+		}))
+	}
+	schedule()
+	return timer
+}
+
+// Tick 以固定频率于消息执行器中执行 fn, 适合房间帧循环等游戏 tick
+// fn 的参数为距上次执行的实际间隔; 上一次 tick 尚未执行或 worker 队列已满时本次合并, 不会堆积
+func (m *Mesh) Tick(interval time.Duration, fn func(dt time.Duration), opts ...TimerOption) *Timer {
+	var (
+		key     = timerKeyOf(opts)
+		timer   = &Timer{}
+		pending atomic.Bool
+		last    = time.Now()
+	)
+	timer.set(m.wheel.Every(interval, func() {
+		if !pending.CompareAndSwap(false, true) {
+			return
+		}
+		ok := m.exec.trySubmit(key, task{fn: func() {
+			pending.Store(false)
+			if timer.stopped.Load() {
+				return
+			}
+			now := time.Now()
+			dt := now.Sub(last)
+			last = now
+			fn(dt)
+		}})
+		if !ok {
+			pending.Store(false)
+			log.Warnf("mesh tick dropped, worker queue is full, key: %v", key)
+		}
+	}))
+	return timer
+}
@@ -0,0 +1,118 @@
+package mesh
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// startScheduler 启动时间轮与单个 worker, 不依赖 broker
+func startScheduler(t *testing.T) *Mesh {
+	t.Helper()
+	m := New(TimerTick(time.Millisecond))
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	m.ctx = ctx
+	go m.wheel.Run(ctx)
+	go m.exec.run(ctx, nil, 0, m.handleTask)
+	return m
+}
+
+// TestSchedulerRunsOnExecutor 验证定时器回调与消息在同一执行器上串行执行
+func TestSchedulerRunsOnExecutor(t *testing.T) {
+	m := startScheduler(t)
+
+	var (
+		running atomic.Int32
+		overlap atomic.Bool
+		count   atomic.Int32
+	)
+	guard := func() {
+		if running.Add(1) > 1 {
+			overlap.Store(true)
+		}
+		time.Sleep(100 * time.Microsecond)
+		running.Add(-1)
+		count.Add(1)
+	}
+	done := make(chan struct{})
+	m.AfterFunc(5*time.Millisecond, func() { close(done) })
+	every := m.Every(time.Millisecond, guard)
+	tick := m.Tick(time.Millisecond, func(time.Duration) { guard() })
+	for range 50 {
+		m.post(0, guard)
+	}
+	<-done
+
+	deadline := time.Now().Add(time.Second)
+	for count.Load() < 80 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	every.Stop()
+	tick.Stop()
+	if overlap.Load() {
+		t.Fatalf("timer callbacks ran concurrently with messages")
+	}
+}
+
+// TestTimerStop 验证取消后的定时器不再执行
+func TestTimerStop(t *testing.T) {
+	m := startScheduler(t)
+
+	var fired atomic.Int32
+	m.AfterFunc(5*time.Millisecond, func() { fired.Add(1) }).Stop()
+
+	var (
+		ticks = make(chan time.Duration, 100)
+		tick  atomic.Pointer[Timer]
+		ready = make(chan struct{})
+	)
+	tick.Store(m.Tick(2*time.Millisecond, func(dt time.Duration) {
+		<-ready
+		ticks <- dt
+		if len(ticks) == 3 {
+			tick.Load().Stop()
+		}
+	}))
+	close(ready)
+	time.Sleep(50 * time.Millisecond)
+	if fired.Load() != 0 {
+		t.Fatalf("stopped timer fired")
+	}
+	if len(ticks) != 3 {
+		t.Fatalf("unexpected ticks after stop: %d", len(ticks))
+	}
+	if dt := <-ticks; dt <= 0 {
+		t.Fatalf("unexpected tick delta: %v", dt)
+	}
+}
+
+// TestTimerDoesNotBlockWheel 验证 worker 队列已满时时间轮不阻塞, 队列空出后定时器回调仍会执行
+func TestTimerDoesNotBlockWheel(t *testing.T) {
+	m := New(TimerTick(time.Millisecond), MessageBufferSize(1))
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	m.ctx = ctx
+	go m.wheel.Run(ctx)
+
+	m.post(0, func() {}) // 占满队列
+	fired := make(chan struct{})
+	m.AfterFunc(time.Millisecond, func() { close(fired) })
+	m.Tick(time.Millisecond, func(time.Duration) {})
+
+	wheel := make(chan struct{})
+	m.wheel.AfterFunc(10*time.Millisecond, func() { close(wheel) })
+	select {
+	case <-wheel:
+	case <-time.After(time.Second):
+		t.Fatal("time wheel blocked by full worker queue")
+	}
+
+	go m.exec.run(ctx, nil, 0, m.handleTask)
+	select {
+	case <-fired:
+	case <-time.After(time.Second):
+		t.Fatal("timer callback not retried after queue drained")
+	}
+}
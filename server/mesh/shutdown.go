@@ -0,0 +1,52 @@
+package mesh
+
+import (
+	"context"
+
+	"github.com/byteweap/meta/component/log"
+)
+
+// ShutdownHandler 注册停机钩子
+// 在停止订阅并处理完已缓冲的消息、worker 退出后, 按注册顺序执行, 如关闭 actor 系统、保存全局状态;
+// ctx 独立于 Stop 传入的上下文, 超时时间由 mesh.ShutdownTimeout 设置, 所有停机钩子共用
+func (m *Mesh) ShutdownHandler(handler func(ctx context.Context)) {
+	if handler == nil {
+		return
+	}
+	m.shutdownHandlers = append(m.shutdownHandlers, handler)
+}
+
+// PlayerShutdownHandler 注册玩家停机钩子
+// 停机时对每个绑定到本节点的玩家(Bind/BindTo/AutoBind)执行, 先于 ShutdownHandler, 如保存玩家数据
+func (m *Mesh) PlayerShutdownHandler(handler func(ctx context.Context, uid int64)) {
+	if handler == nil {
+		return
+	}
+	m.playerShutdownHandlers = append(m.playerShutdownHandlers, handler)
+}
+
+// shutdown 执行停机钩子, 单个钩子异常不影响其余钩子
+func (m *Mesh) shutdown(ctx context.Context) {
+	if len(m.playerShutdownHandlers) > 0 {
+		m.bound.Range(func(key, _ any) bool {
+			uid := key.(int64)
+			for _, handler := range m.playerShutdownHandlers {
+				m.safeShutdown(func() { handler(ctx, uid) })
+			}
+			return true
+		})
+	}
+	for _, handler := range m.shutdownHandlers {
+		m.safeShutdown(func() { handler(ctx) })
+	}
+}
+
+func (m *Mesh) safeShutdown(fn func()) {
+	defer func() {
+		if e := recover(); e != nil {
+			m.panics.Add(1)
+			log.Errorf("mesh shutdown handler panic: %v", e)
+		}
+	}()
+	fn()
+}
@@ -0,0 +1,138 @@
+package mesh
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/byteweap/meta"
+	"github.com/byteweap/meta/component/broker"
+	"github.com/byteweap/meta/envelope"
+	"github.com/byteweap/meta/internal/cluster"
+)
+
+type testApp struct{}
+
+func (testApp) ID() string                  { return "game-1" }
+func (testApp) Name() string                { return "game" }
+func (testApp) Version() string             { return "v1.0.0" }
+func (testApp) Metadata() map[string]string { return nil }
+func (testApp) Endpoint() []string          { return nil }
+
+// startMesh 启动 mesh 并等待完成订阅
+func startMesh(t *testing.T, m *Mesh, mb *mockBroker) {
+	t.Helper()
+	go func() { _ = m.Start(meta.NewContext(context.Background(), testApp{})) }()
+	deadline := time.Now().Add(time.Second)
+	for {
+		mb.mu.Lock()
+		handler := mb.subHandler
+		mb.mu.Unlock()
+		if handler != nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("mesh not subscribed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// TestStopDrainsBufferedMessages 验证停止时先取消订阅, 处理完已缓冲的消息后执行停机钩子
+func TestStopDrainsBufferedMessages(t *testing.T) {
+	mb := &mockBroker{}
+	m := New(Broker(mb), Locator(&mockLocator{}))
+
+	var handled atomic.Int32
+	m.RouteX(5001, 1, func(_ *Context, _ *envelope.Header) {
+		time.Sleep(5 * time.Millisecond)
+		handled.Add(1)
+	})
+	var saved, closed atomic.Int32
+	m.PlayerShutdownHandler(func(_ context.Context, uid int64) {
+		if handled.Load() == 5 && uid == 1001 {
+			saved.Add(1)
+		}
+	})
+	m.ShutdownHandler(func(context.Context) {
+		closed.Add(1)
+	})
+
+	startMesh(t, m, mb)
+	if err := m.BindTo(1001, "game-1"); err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+
+	header := cluster.BuildHeader(1001, cluster.Event_Business, "gate.reply", "gate", "game")
+	for range 5 {
+		raw := mustBusinessMessage(t, 5001, 1, "game", &envelope.Header{})
+		mb.subHandler(&broker.Message{Header: header, Data: raw})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := m.Stop(ctx); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	if handled.Load() != 5 {
+		t.Fatalf("buffered messages not drained: %d", handled.Load())
+	}
+	if saved.Load() != 1 || closed.Load() != 1 {
+		t.Fatalf("shutdown hooks not executed: saved=%d closed=%d", saved.Load(), closed.Load())
+	}
+	if mb.subClosed != 1 {
+		t.Fatalf("subscription not closed: %d", mb.subClosed)
+	}
+}
+
+// TestStopDrainTimeout 验证排空超时后先停止 worker, 再以新的上下文执行停机钩子
+func TestStopDrainTimeout(t *testing.T) {
+	mb := &mockBroker{}
+	m := New(Broker(mb), Locator(&mockLocator{}), ShutdownTimeout(time.Second))
+
+	var running, handled atomic.Int32
+	m.RouteX(5001, 1, func(_ *Context, _ *envelope.Header) {
+		running.Add(1)
+		time.Sleep(30 * time.Millisecond)
+		handled.Add(1)
+		running.Add(-1)
+	})
+	hook := make(chan error, 1)
+	m.ShutdownHandler(func(ctx context.Context) {
+		if running.Load() != 0 {
+			hook <- errors.New("handler still running")
+			return
+		}
+		if _, ok := ctx.Deadline(); !ok || ctx.Err() != nil {
+			hook <- errors.New("hook context should be fresh and bounded")
+			return
+		}
+		hook <- nil
+	})
+	startMesh(t, m, mb)
+
+	header := cluster.BuildHeader(1001, cluster.Event_Business, "gate.reply", "gate", "game")
+	for range 10 {
+		raw := mustBusinessMessage(t, 5001, 1, "game", &envelope.Header{})
+		mb.subHandler(&broker.Message{Header: header, Data: raw})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := m.Stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected drain timeout, got %v", err)
+	}
+	select {
+	case err := <-hook:
+		if err != nil {
+			t.Fatal(err)
+		}
+	default:
+		t.Fatal("shutdown hook not executed")
+	}
+	if n := handled.Load(); n == 0 || n == 10 {
+		t.Fatalf("unexpected handled count: %d", n)
+	}
+}
@@ -2,16 +2,42 @@ package mesh
 
 import (
 	"context"
+	"sync"
 
 	"github.com/byteweap/meta/component/broker"
 )
 
-type mockSubscription struct{}
+type mockSubscription struct {
+	b *mockBroker
+}
 
 func (s *mockSubscription) Unsub() error { return nil }
-func (s *mockSubscription) Close() error { return nil }
+func (s *mockSubscription) Close() error {
+	if s.b != nil {
+		s.b.mu.Lock()
+		s.b.subClosed++
+		s.b.mu.Unlock()
+	}
+	return nil
+}
 
 type mockBroker struct {
+	mu sync.Mutex
+
+	pubCalls int
+	pubData  []byte
+
+	subSubjects []string
+	subQueues   []string
+	subHandler  broker.Handler
+	subClosed   int
+
+	reqSubject string
+	reqHeader  broker.Header
+	reqData    []byte
+	reqResp    *broker.Message
+	reqFunc    func(ctx context.Context, subject string, header broker.Header, data []byte) (*broker.Message, error)
+
 	replyCalls int
 	replyData  []byte
 	replyHdr   broker.Header
@@ -20,14 +46,40 @@ type mockBroker struct {
 func (b *mockBroker) ID() string { return "mock" }
 
 func (b *mockBroker) Pub(ctx context.Context, subject string, data []byte, opts ...broker.PublishOption) error {
+	b.pubCalls++
+	b.pubData = data
 	return nil
 }
 
 func (b *mockBroker) Sub(ctx context.Context, subject string, handler broker.Handler, opts ...broker.SubscribeOption) (broker.Subscription, error) {
-	return &mockSubscription{}, nil
+	subOpt := &broker.SubscribeOptions{}
+	for _, opt := range opts {
+		opt(subOpt)
+	}
+	b.mu.Lock()
+	b.subSubjects = append(b.subSubjects, subject)
+	b.subQueues = append(b.subQueues, subOpt.Queue)
+	b.subHandler = handler
+	b.mu.Unlock()
+	return &mockSubscription{b: b}, nil
 }
 
 func (b *mockBroker) Request(ctx context.Context, subject string, data []byte, opts ...broker.RequestOption) (*broker.Message, error) {
+	reqOpt := &broker.RequestOptions{}
+	for _, opt := range opts {
+		opt(reqOpt)
+	}
+	b.mu.Lock()
+	b.reqSubject = subject
+	b.reqHeader = reqOpt.Header
+	b.reqData = data
+	b.mu.Unlock()
+	if b.reqFunc != nil {
+		return b.reqFunc(ctx, subject, reqOpt.Header, data)
+	}
+	if b.reqResp != nil {
+		return b.reqResp, nil
+	}
 	return &broker.Message{}, nil
 }
 
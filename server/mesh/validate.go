@@ -0,0 +1,247 @@
+package mesh
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	es "github.com/byteweap/meta/errors"
+)
+
+// ReasonValidationFailed 请求参数校验失败
+const ReasonValidationFailed = "VALIDATION_FAILED"
+
+// Validatable 自带校验方法的消息, 如 protoc-gen-validate 按字段选项生成的 Validate 方法
+type Validatable interface {
+	Validate() error
+}
+
+// Rule 字段校验规则, 字段名为 proto 定义中的字段名
+type Rule struct {
+	field string
+	kind  ruleKind
+	min   float64
+	max   float64
+}
+
+type ruleKind uint8
+
+const (
+	ruleRequired ruleKind = iota
+	ruleRange
+	ruleLength
+	ruleEnum
+)
+
+// Required 字段必填: 标量非零值, 字符串/bytes/repeated/map 非空, 消息已设置
+func Required(field string) Rule {
+	return Rule{field: field, kind: ruleRequired}
+}
+
+// Range 数值字段取值范围 [min, max]
+func Range(field string, min, max float64) Rule {
+	return Rule{field: field, kind: ruleRange, min: min, max: max}
+}
+
+// Length 长度范围 [min, max], 字符串按字符数计算, bytes 按字节数计算, repeated/map 按元素个数计算
+func Length(field string, min, max int) Rule {
+	return Rule{field: field, kind: ruleLength, min: float64(min), max: float64(max)}
+}
+
+// Enum 枚举字段取值必须为枚举中定义的值
+func Enum(field string) Rule {
+	return Rule{field: field, kind: ruleEnum}
+}
+
+// fieldRule 绑定字段描述后的校验规则
+type fieldRule struct {
+	fd    protoreflect.FieldDescriptor
+	check func(m protoreflect.Message, fd protoreflect.FieldDescriptor) string
+}
+
+var validators sync.Map // protoreflect.FullName -> []fieldRule
+
+// RegisterValidator 为消息类型注册校验规则, Wrap/WrapRpc 等包装器解析参数后自动校验
+// 重复注册时覆盖, 字段不存在或字段类型与规则不匹配时 panic, 应在初始化阶段调用
+// 示例: mesh.RegisterValidator(&pb.LoginReq{}, mesh.Required("token"), mesh.Length("name", 1, 16))
+func RegisterValidator(msg proto.Message, rules ...Rule) {
+	md := msg.ProtoReflect().Descriptor()
+	frs := make([]fieldRule, 0, len(rules))
+	for _, r := range rules {
+		fd := md.Fields().ByName(protoreflect.Name(r.field))
+		if fd == nil {
+			panic(fmt.Sprintf("mesh: validator field %q not found in %s", r.field, md.FullName()))
+		}
+		check, err := r.compile(fd)
+		if err != nil {
+			panic(fmt.Sprintf("mesh: validator field %s.%s: %v", md.FullName(), r.field, err))
+		}
+		frs = append(frs, fieldRule{fd: fd, check: check})
+	}
+	validators.Store(md.FullName(), frs)
+}
+
+// compile 校验规则与字段类型是否匹配, 返回字段检查函数, 检查函数返回违规描述, 合法时返回空串
+func (r Rule) compile(fd protoreflect.FieldDescriptor) (func(protoreflect.Message, protoreflect.FieldDescriptor) string, error) {
+	switch r.kind {
+	case ruleRequired:
+		return checkRequired, nil
+	case ruleRange:
+		if fd.IsList() || fd.IsMap() || !isNumeric(fd.Kind()) {
+			return nil, fmt.Errorf("range rule requires numeric field, got %s", fd.Kind())
+		}
+		return r.checkRange, nil
+	case ruleLength:
+		if !fd.IsList() && !fd.IsMap() && fd.Kind() != protoreflect.StringKind && fd.Kind() != protoreflect.BytesKind {
+			return nil, fmt.Errorf("length rule requires string, bytes, repeated or map field, got %s", fd.Kind())
+		}
+		return r.checkLength, nil
+	case ruleEnum:
+		if fd.IsMap() || fd.Kind() != protoreflect.EnumKind {
+			return nil, fmt.Errorf("enum rule requires enum field, got %s", fd.Kind())
+		}
+		return checkEnum, nil
+	default:
+		return nil, fmt.Errorf("unknown rule kind %d", r.kind)
+	}
+}
+
+func checkRequired(m protoreflect.Message, fd protoreflect.FieldDescriptor) string {
+	if !m.Has(fd) {
+		return "required"
+	}
+	return ""
+}
+
+func (r Rule) checkRange(m protoreflect.Message, fd protoreflect.FieldDescriptor) string {
+	var (
+		v = m.Get(fd)
+		n float64
+	)
+	switch fd.Kind() {
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		n = v.Float()
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		n = float64(v.Uint())
+	default:
+		n = float64(v.Int())
+	}
+	if n < r.min || n > r.max {
+		return fmt.Sprintf("must be in [%g, %g]", r.min, r.max)
+	}
+	return ""
+}
+
+func (r Rule) checkLength(m protoreflect.Message, fd protoreflect.FieldDescriptor) string {
+	var (
+		v = m.Get(fd)
+		n int
+	)
+	switch {
+	case fd.IsList():
+		n = v.List().Len()
+	case fd.IsMap():
+		n = v.Map().Len()
+	case fd.Kind() == protoreflect.StringKind:
+		n = utf8.RuneCountInString(v.String())
+	default:
+		n = len(v.Bytes())
+	}
+	if float64(n) < r.min || float64(n) > r.max {
+		return fmt.Sprintf("length must be in [%g, %g]", r.min, r.max)
+	}
+	return ""
+}
+
+func checkEnum(m protoreflect.Message, fd protoreflect.FieldDescriptor) string {
+	values := fd.Enum().Values()
+	if fd.IsList() {
+		list := m.Get(fd).List()
+		for i := range list.Len() {
+			if values.ByNumber(list.Get(i).Enum()) == nil {
+				return "invalid enum value"
+			}
+		}
+		return ""
+	}
+	if values.ByNumber(m.Get(fd).Enum()) == nil {
+		return "invalid enum value"
+	}
+	return ""
+}
+
+func isNumeric(k protoreflect.Kind) bool {
+	switch k {
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind,
+		protoreflect.FloatKind, protoreflect.DoubleKind:
+		return true
+	}
+	return false
+}
+
+// validate 校验已解析的请求参数, 未注册规则且未实现 Validatable 时直接通过
+// 校验失败返回 400 错误, Metadata 中为各违规字段及原因
+func validate(v any) error {
+	if vd, ok := v.(Validatable); ok {
+		if err := vd.Validate(); err != nil {
+			var e *es.Error
+			if es.As(err, &e) {
+				return e
+			}
+			return es.BadRequest(ReasonValidationFailed, err.Error()).WithCause(err)
+		}
+	}
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return nil
+	}
+	rm := msg.ProtoReflect()
+	rules, ok := validators.Load(rm.Descriptor().FullName())
+	if !ok {
+		return nil
+	}
+	var (
+		fields []string
+		md     map[string]string
+	)
+	for _, r := range rules.([]fieldRule) {
+		name := string(r.fd.Name())
+		if _, exists := md[name]; exists {
+			continue
+		}
+		if reason := r.check(rm, r.fd); reason != "" {
+			if md == nil {
+				md = make(map[string]string)
+			}
+			md[name] = reason
+			fields = append(fields, name)
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return es.BadRequest(ReasonValidationFailed, "invalid fields: "+strings.Join(fields, ", ")).WithMetadata(md)
+}
+
+// validatePtr 校验请求参数, 参数为 nil(空 payload)时按零值消息校验
+func validatePtr[T any](v *T) error {
+	if v == nil {
+		v = new(T)
+	}
+	return validate(v)
+}
+
+// validateArg 校验反射适配的 handler 参数, 参数为 nil(空 payload)时按零值消息校验
+func validateArg(arg reflect.Value, argType reflect.Type) error {
+	if arg.IsNil() {
+		arg = reflect.New(argType.Elem())
+	}
+	return validate(arg.Interface())
+}
@@ -0,0 +1,83 @@
+package mesh
+
+import (
+	"testing"
+
+	"github.com/byteweap/meta/component/broker"
+	"github.com/byteweap/meta/encoding/proto"
+	"github.com/byteweap/meta/envelope"
+	es "github.com/byteweap/meta/errors"
+	"github.com/byteweap/meta/internal/cluster"
+)
+
+// TestValidateRules 验证必填、取值范围、长度与枚举规则
+func TestValidateRules(t *testing.T) {
+	RegisterValidator(&envelope.OMessage{},
+		Required("header"),
+		Length("service", 1, 4),
+		Enum("msg_type"),
+	)
+	RegisterValidator(&envelope.Header{}, Range("cmd", 1, 100))
+	t.Cleanup(func() {
+		validators.Delete((&envelope.OMessage{}).ProtoReflect().Descriptor().FullName())
+		validators.Delete((&envelope.Header{}).ProtoReflect().Descriptor().FullName())
+	})
+
+	if err := validate(&envelope.OMessage{Header: &envelope.Header{}, Service: "game"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err := validate(&envelope.OMessage{Service: "lobby", MsgType: 99})
+	e := toCode(es.FromError(err))
+	if e.GetCode() != 400 || e.GetReason() != ReasonValidationFailed || len(e.GetMetadata()) != 3 {
+		t.Fatalf("unexpected error: %+v", e)
+	}
+	if e.GetTip() != "invalid fields: header, service, msg_type" {
+		t.Fatalf("unexpected tip: %s", e.GetTip())
+	}
+	if err = validate(&envelope.Header{Cmd: 101}); err == nil {
+		t.Fatal("expected range error")
+	}
+}
+
+// TestRegisterValidatorInvalidRule 验证字段不存在或类型不匹配时 panic
+func TestRegisterValidatorInvalidRule(t *testing.T) {
+	for _, rule := range []Rule{Required("missing"), Range("service", 0, 1), Enum("cmd")} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("expected panic for rule %+v", rule)
+				}
+			}()
+			RegisterValidator(&envelope.IMessage{}, rule)
+		}()
+	}
+}
+
+// TestWrapValidate 验证 Wrap 校验失败时回复错误且不调用 handler, 空 payload 按零值校验
+func TestWrapValidate(t *testing.T) {
+	RegisterValidator(&envelope.Header{}, Range("cmd", 1, 100))
+	t.Cleanup(func() {
+		validators.Delete((&envelope.Header{}).ProtoReflect().Descriptor().FullName())
+	})
+
+	mb := &mockBroker{}
+	m := New(Broker(mb))
+	called := 0
+	m.Route(6201, 1, Wrap(func(_ *Context, _ *envelope.Header) { called++ }))
+
+	header := cluster.BuildHeader(1001, cluster.Event_Business, "gate.reply", "gate", "game")
+	for _, payload := range []*envelope.Header{{Cmd: 101}, {}} {
+		m.handleTask(task{msg: &broker.Message{Header: header, Data: mustBusinessMessage(t, 6201, 1, "game", payload)}})
+		out := &envelope.OMessage{}
+		if err := proto.Unmarshal(mb.pubData, out); err != nil {
+			t.Fatalf("unmarshal response: %v", err)
+		}
+		if r := out.GetResult(); r.GetCode() != 400 || r.GetMetadata()["cmd"] == "" {
+			t.Fatalf("unexpected response: %+v", r)
+		}
+	}
+	m.handleTask(task{msg: &broker.Message{Header: header, Data: mustBusinessMessage(t, 6201, 1, "game", &envelope.Header{Cmd: 5})}})
+	if called != 1 {
+		t.Fatalf("handler called %d times", called)
+	}
+}
@@ -0,0 +1,169 @@
+package mesh
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/byteweap/meta/encoding/proto"
+	es "github.com/byteweap/meta/errors"
+)
+
+// maxUpgradeHops 单条消息最多连续升级的版本数, 防止转换器成环
+const maxUpgradeHops = 8
+
+// VersionPolicy 路由版本策略, 决定请求版本未注册时的处理方式
+type VersionPolicy int
+
+const (
+	// VersionExact 仅精确匹配版本(默认), 未匹配时回复 ReasonUnsupportedVersion 错误
+	VersionExact VersionPolicy = iota
+	// VersionFallback 回退到不高于请求版本的最高已注册版本
+	// 适用于新增字段等向后兼容的变更, 旧版本 handler 忽略新字段
+	VersionFallback
+)
+
+// Converter 负载升级函数, 将旧版本的 payload 转换为新版本
+type Converter func(payload []byte) ([]byte, error)
+
+// upgrade 版本升级: from -> to
+type upgrade struct {
+	to uint32
+	fn Converter
+}
+
+// Convert 构造基于 proto 编解码的负载升级函数
+//
+// 示例:
+//
+//	m.Upgrade(cmd, 1, 2, mesh.Convert(func(old *pb.EnterV1) (*pb.EnterV2, error) {
+//		return &pb.EnterV2{RoomId: old.RoomId, Mode: pb.Mode_NORMAL}, nil
+//	}))
+func Convert[From, To any](fn func(*From) (*To, error)) Converter {
+	return func(payload []byte) ([]byte, error) {
+		from := new(From)
+		if len(payload) > 0 {
+			if err := proto.Unmarshal(payload, from); err != nil {
+				return nil, err
+			}
+		}
+		to, err := fn(from)
+		if err != nil {
+			return nil, err
+		}
+		return proto.Marshal(to)
+	}
+}
+
+// SetVersionPolicy 设置 cmd 的版本策略, 未设置时使用 mesh.DefaultVersionPolicy 配置
+func (m *Mesh) SetVersionPolicy(cmd uint32, policy VersionPolicy) {
+	m.vmu.Lock()
+	defer m.vmu.Unlock()
+	if m.policies == nil {
+		m.policies = make(map[uint32]VersionPolicy)
+	}
+	m.policies[cmd] = policy
+}
+
+// Upgrade 注册负载升级函数, 请求版本 from 未注册时将 payload 升级到 to 版本后交由 to 版本 handler 处理
+// 可链式注册(1 -> 2 -> 3), 优先于 VersionFallback 回退
+func (m *Mesh) Upgrade(cmd, from, to uint32, fn Converter) {
+	if fn == nil {
+		panic("mesh: converter is nil")
+	}
+	m.vmu.Lock()
+	defer m.vmu.Unlock()
+	if m.upgrades == nil {
+		m.upgrades = make(map[uint64]upgrade)
+	}
+	m.upgrades[routeKey(cmd, from)] = upgrade{to: to, fn: fn}
+}
+
+// addVersion 记录 cmd 已注册的版本
+func (m *Mesh) addVersion(cmd, version uint32) {
+	m.vmu.Lock()
+	defer m.vmu.Unlock()
+	if m.versions == nil {
+		m.versions = make(map[uint32][]uint32)
+	}
+	vs := m.versions[cmd]
+	if i, found := slices.BinarySearch(vs, version); !found {
+		m.versions[cmd] = slices.Insert(vs, i, version)
+	}
+}
+
+// hop 一次版本升级
+type hop struct {
+	from uint32
+	upgrade
+}
+
+// resolveRoute 按版本策略解析处理请求的已注册版本, 不转换负载
+// 依次尝试: 精确匹配 -> 升级(返回经过的升级链) -> 回退; ok 为 false 时 versions 为 cmd 已注册的版本
+func (m *Mesh) resolveRoute(cmd, version uint32) (to uint32, hops []hop, versions []uint32, ok bool) {
+	if _, found := m.routes.Load(routeKey(cmd, version)); found {
+		return version, nil, nil, true
+	}
+
+	m.vmu.RLock()
+	defer m.vmu.RUnlock()
+	versions = m.versions[cmd]
+	if len(versions) == 0 {
+		return 0, nil, nil, false
+	}
+
+	// 升级
+	v := version
+	for range maxUpgradeHops {
+		up, found := m.upgrades[routeKey(cmd, v)]
+		if !found {
+			break
+		}
+		hops = append(hops, hop{from: v, upgrade: up})
+		v = up.to
+		if _, found = m.routes.Load(routeKey(cmd, v)); found {
+			return v, hops, versions, true
+		}
+	}
+
+	// 回退
+	policy, found := m.policies[cmd]
+	if !found {
+		policy = m.opts.versionPolicy
+	}
+	if policy == VersionFallback {
+		if i, _ := slices.BinarySearch(versions, version); i > 0 {
+			if _, found = m.routes.Load(routeKey(cmd, versions[i-1])); found {
+				return versions[i-1], nil, versions, true
+			}
+		}
+	}
+	return 0, nil, versions, false
+}
+
+// matchRoute 按版本策略查找路由
+// 返回的 payload 为升级后的负载; 未找到时 cmd 有其它已注册版本则返回 ReasonUnsupportedVersion 错误,
+// 否则 handler 与错误均为 nil
+func (m *Mesh) matchRoute(cmd, version uint32, payload []byte) (MessageHandler, []byte, error) {
+	to, hops, versions, ok := m.resolveRoute(cmd, version)
+	if !ok {
+		if len(versions) == 0 {
+			return nil, payload, nil
+		}
+		return nil, payload, es.BadRequest(ReasonUnsupportedVersion,
+			fmt.Sprintf("cmd:%d version:%d unsupported, supported versions: %v", cmd, version, versions))
+	}
+	p := payload
+	for _, h := range hops {
+		var err error
+		if p, err = h.fn(p); err != nil {
+			return nil, payload, es.BadRequest(ReasonUnsupportedVersion,
+				fmt.Sprintf("cmd:%d upgrade payload from version %d to %d failed", cmd, h.from, h.to)).WithCause(err)
+		}
+	}
+	handler, ok := m.routes.Load(routeKey(cmd, to))
+	if !ok {
+		return nil, payload, es.BadRequest(ReasonUnsupportedVersion,
+			fmt.Sprintf("cmd:%d version:%d unsupported, supported versions: %v", cmd, version, versions))
+	}
+	return handler.(MessageHandler), p, nil
+}
@@ -0,0 +1,65 @@
+package mesh
+
+import (
+	"testing"
+
+	"github.com/byteweap/meta/component/broker"
+	"github.com/byteweap/meta/encoding/proto"
+	"github.com/byteweap/meta/envelope"
+	"github.com/byteweap/meta/internal/cluster"
+)
+
+func sendVersion(t *testing.T, m *Mesh, cmd, version uint32, payload *envelope.Header) {
+	t.Helper()
+	header := cluster.BuildHeader(1001, cluster.Event_Business, "gate.reply", "gate", "game")
+	raw := mustBusinessMessage(t, cmd, version, "game", payload)
+	m.handleTask(task{msg: &broker.Message{Header: header, Data: raw}})
+}
+
+// TestVersionUpgrade 验证旧版本负载经转换器链式升级后交由新版本 handler 处理
+func TestVersionUpgrade(t *testing.T) {
+	m := New(Broker(&mockBroker{}))
+
+	var got *envelope.Header
+	m.RouteX(7, 3, func(_ *Context, req *envelope.Header) { got = req })
+	m.Upgrade(7, 1, 2, Convert(func(old *envelope.Header) (*envelope.Header, error) {
+		return &envelope.Header{Seq: old.GetSeq() + 1}, nil
+	}))
+	m.Upgrade(7, 2, 3, Convert(func(old *envelope.Header) (*envelope.Header, error) {
+		return &envelope.Header{Seq: old.GetSeq() * 10}, nil
+	}))
+
+	sendVersion(t, m, 7, 1, &envelope.Header{Seq: 1})
+	if got.GetSeq() != 20 {
+		t.Fatalf("unexpected upgraded payload: %+v", got)
+	}
+}
+
+// TestVersionFallbackAndUnsupported 验证版本回退策略与不支持版本的错误响应
+func TestVersionFallbackAndUnsupported(t *testing.T) {
+	mb := &mockBroker{}
+	m := New(Broker(mb))
+
+	called := 0
+	m.RouteX(8, 1, func(_ *Context, _ *envelope.Header) { called++ })
+	m.RouteX(9, 1, func(_ *Context, _ *envelope.Header) { called++ })
+	m.SetVersionPolicy(8, VersionFallback)
+
+	sendVersion(t, m, 8, 2, &envelope.Header{})
+	if called != 1 || mb.pubCalls != 0 {
+		t.Fatalf("expected fallback to version 1, called=%d pubs=%d", called, mb.pubCalls)
+	}
+
+	sendVersion(t, m, 9, 2, &envelope.Header{})
+	if called != 1 || mb.pubCalls != 1 {
+		t.Fatalf("expected unsupported version response, called=%d pubs=%d", called, mb.pubCalls)
+	}
+	out := &envelope.OMessage{}
+	if err := proto.Unmarshal(mb.pubData, out); err != nil {
+		t.Fatalf("unmarshal response: %v", err)
+	}
+	if out.GetResult().GetCode() != 400 || out.GetResult().GetReason() != ReasonUnsupportedVersion {
+		t.Fatalf("unexpected response: %+v", out.GetResult())
+	}
+
+}
@@ -18,3 +18,9 @@ type Server interface {
 	Stop(ctx context.Context) error
 	Endpoint(ctx context.Context) (*url.URL, error)
 }
+
+// MetadataProvider 可选接口
+// 服务实现后, 其元数据在注册时合并到服务实例元数据, 应用配置的同名元数据优先
+type MetadataProvider interface {
+	Metadata() map[string]string
+}